@@ -2,11 +2,10 @@ module github.com/dhlanshan/lotus
 
 go 1.23.3
 
-require github.com/google/uuid v1.6.0
-
 require (
-	github.com/matoous/go-nanoid/v2 v2.1.0 // indirect
-	github.com/oklog/ulid/v2 v2.1.1 // indirect
-	github.com/rs/xid v1.6.0 // indirect
-	github.com/segmentio/ksuid v1.0.4 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/matoous/go-nanoid/v2 v2.1.0
+	github.com/oklog/ulid/v2 v2.1.1
+	github.com/rs/xid v1.6.0
+	github.com/segmentio/ksuid v1.0.4
 )
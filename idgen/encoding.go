@@ -0,0 +1,210 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// Encoding 基于字母表的数值编码，字母表按 ASCII 升序排列时，定长编码的字典序与数值大小一致
+type Encoding struct {
+	name     string
+	alphabet string
+	index    [256]byte // 字符到数值的映射，invalidIndex 表示非法字符
+}
+
+const invalidIndex = 0xff
+
+// 内置编码，字母表均按 ASCII 升序排列
+var (
+	Decimal         = mustEncoding("decimal", "0123456789")
+	Hex             = mustEncoding("hex", "0123456789abcdef")
+	Base32Crockford = mustEncoding("base32", "0123456789ABCDEFGHJKMNPQRSTVWXYZ")
+	Base58          = mustEncoding("base58", "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
+	Base62          = mustEncoding("base62", "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+)
+
+func init() {
+	// 十六进制解码不区分大小写
+	for i, c := range "ABCDEF" {
+		Hex.index[c] = byte(10 + i)
+	}
+	// Crockford base32 解码不区分大小写，I / L 视为 1，O 视为 0
+	for i, c := range Base32Crockford.alphabet {
+		Base32Crockford.index[strings.ToLower(string(c))[0]] = byte(i)
+	}
+	for _, c := range "IiLl" {
+		Base32Crockford.index[c] = 1
+	}
+	for _, c := range "Oo" {
+		Base32Crockford.index[c] = 0
+	}
+}
+
+// NewEncoding 使用自定义字母表创建编码，字母表需为 2-255 个不重复的 ASCII 字符
+func NewEncoding(name string, alphabet string) (*Encoding, error) {
+	if len(alphabet) < 2 || len(alphabet) >= invalidIndex {
+		return nil, fmt.Errorf("idgen: encoding %s: alphabet size %d out of range [2, 254]", name, len(alphabet))
+	}
+	enc := &Encoding{name: name, alphabet: alphabet}
+	for i := range enc.index {
+		enc.index[i] = invalidIndex
+	}
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c >= 0x80 {
+			return nil, fmt.Errorf("idgen: encoding %s: alphabet contains non-ASCII byte %#x", name, c)
+		}
+		if enc.index[c] != invalidIndex {
+			return nil, fmt.Errorf("idgen: encoding %s: alphabet contains duplicate %q", name, c)
+		}
+		enc.index[c] = byte(i)
+	}
+	return enc, nil
+}
+
+func mustEncoding(name string, alphabet string) *Encoding {
+	enc, err := NewEncoding(name, alphabet)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// Name 编码名称
+func (enc *Encoding) Name() string {
+	return enc.name
+}
+
+// Width 定长编码 bits 位无符号数所需的字符数
+func (enc *Encoding) Width(bits int) int {
+	return int(math.Ceil(float64(bits) / math.Log2(float64(len(enc.alphabet)))))
+}
+
+// EncodeUint64 以最短形式编码 v
+func (enc *Encoding) EncodeUint64(v uint64) string {
+	return string(enc.appendUint64(nil, v, 0))
+}
+
+// EncodeUint64Fixed 以 64 位定长形式编码 v，左侧补零字符，字典序与数值大小一致
+func (enc *Encoding) EncodeUint64Fixed(v uint64) string {
+	return string(enc.appendUint64(nil, v, enc.Width(64)))
+}
+
+func (enc *Encoding) appendUint64(dst []byte, v uint64, width int) []byte {
+	var buf [64]byte
+	i := len(buf)
+	base := uint64(len(enc.alphabet))
+	for v >= base {
+		i--
+		buf[i] = enc.alphabet[v%base]
+		v /= base
+	}
+	i--
+	buf[i] = enc.alphabet[v]
+	for len(buf)-i < width {
+		i--
+		buf[i] = enc.alphabet[0]
+	}
+	return append(dst, buf[i:]...)
+}
+
+// DecodeUint64 解码 EncodeUint64 或 EncodeUint64Fixed 的结果，非法字符或溢出时返回 *ParseError
+func (enc *Encoding) DecodeUint64(s string) (uint64, error) {
+	if s == "" {
+		return 0, malformedf(enc.name, s, "empty")
+	}
+	base := uint64(len(enc.alphabet))
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		digit := enc.index[s[i]]
+		if digit == invalidIndex {
+			return 0, malformedf(enc.name, s, "invalid character %q at position %d", s[i], i)
+		}
+		if v > (math.MaxUint64-uint64(digit))/base {
+			return 0, malformedf(enc.name, s, "value overflows uint64")
+		}
+		v = v*base + uint64(digit)
+	}
+	return v, nil
+}
+
+// EncodeBytes 将大端字节序的数值定长编码，宽度只取决于 len(b)，相同长度的输入字典序与字节序一致
+func (enc *Encoding) EncodeBytes(b []byte) string {
+	width := enc.Width(len(b) * 8)
+	v := new(big.Int).SetBytes(b)
+	base := big.NewInt(int64(len(enc.alphabet)))
+	digit := new(big.Int)
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		v.QuoRem(v, base, digit)
+		buf[i] = enc.alphabet[digit.Int64()]
+	}
+	return string(buf)
+}
+
+// DecodeBytes 解码 EncodeBytes 的结果，返回 size 字节的大端数值
+func (enc *Encoding) DecodeBytes(s string, size int) ([]byte, error) {
+	if width := enc.Width(size * 8); len(s) != width {
+		return nil, malformedf(enc.name, s, "length %d, want %d", len(s), width)
+	}
+	v := new(big.Int)
+	base := big.NewInt(int64(len(enc.alphabet)))
+	for i := 0; i < len(s); i++ {
+		digit := enc.index[s[i]]
+		if digit == invalidIndex {
+			return nil, malformedf(enc.name, s, "invalid character %q at position %d", s[i], i)
+		}
+		v.Mul(v, base).Add(v, big.NewInt(int64(digit)))
+	}
+	if v.BitLen() > size*8 {
+		return nil, malformedf(enc.name, s, "value overflows %d bytes", size)
+	}
+	return v.FillBytes(make([]byte, size)), nil
+}
+
+// errNegativeSnowflakeId 雪花 ID 不能为负数
+var errNegativeSnowflakeId = errors.New("negative id")
+
+// EncodeSnowflakeId 将雪花 ID 按 63 位定长编码，字典序与 ID 大小一致，适合对外暴露或作为排序键
+func EncodeSnowflakeId(id int64, enc *Encoding) (string, error) {
+	if id < 0 {
+		return "", malformed("snowflake", fmt.Sprint(id), errNegativeSnowflakeId)
+	}
+	return string(enc.appendUint64(nil, uint64(id), enc.Width(63))), nil
+}
+
+// DecodeSnowflakeId 解码 EncodeSnowflakeId 的结果，同时接受最短形式
+func DecodeSnowflakeId(s string, enc *Encoding) (int64, error) {
+	v, err := enc.DecodeUint64(s)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxInt64 {
+		return 0, malformedf(enc.name, s, "value overflows int64")
+	}
+	return int64(v), nil
+}
+
+// UUIdToULId 将 UUID 的 128 位原样转换为 ULID 字符串，ULIdToUUId 可还原
+func UUIdToULId(id string) (string, error) {
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return "", malformed("uuid", id, err)
+	}
+	return ulid.ULID(u).String(), nil
+}
+
+// ULIdToUUId 将 ULID 的 128 位原样转换为 UUID 字符串，版本和变体位不做修改
+func ULIdToUUId(id string) (string, error) {
+	parsed, err := ParseULId(id)
+	if err != nil {
+		return "", err
+	}
+	return uuid.UUID(parsed.ULID).String(), nil
+}
@@ -0,0 +1,135 @@
+package idgen
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var encodings = []*Encoding{Decimal, Hex, Base32Crockford, Base58, Base62}
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	values := []uint64{0, 1, 57, 58, 61, 62, math.MaxInt64, math.MaxUint64}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Uint64()>>rand.Intn(64))
+	}
+
+	for _, enc := range encodings {
+		t.Run(enc.Name(), func(t *testing.T) {
+			fixed := make([]string, len(values))
+			for i, v := range values {
+				for _, s := range []string{enc.EncodeUint64(v), enc.EncodeUint64Fixed(v)} {
+					if got, err := enc.DecodeUint64(s); err != nil || got != v {
+						t.Fatalf("DecodeUint64(%q) = %d, %v; want %d", s, got, err, v)
+					}
+				}
+				fixed[i] = enc.EncodeUint64Fixed(v)
+				if len(fixed[i]) != enc.Width(64) {
+					t.Fatalf("EncodeUint64Fixed(%d) = %q, want width %d", v, fixed[i], enc.Width(64))
+				}
+			}
+
+			// 定长形式的字典序与数值大小一致
+			sorted := append([]uint64(nil), values...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			sort.Strings(fixed)
+			for i, s := range fixed {
+				if v, _ := enc.DecodeUint64(s); v != sorted[i] {
+					t.Fatalf("fixed-width order differs at %d: %d != %d", i, v, sorted[i])
+				}
+			}
+
+			if _, err := enc.DecodeUint64(strings.Repeat(enc.EncodeUint64(math.MaxUint64), 2)); !errors.Is(err, ErrMalformedId) {
+				t.Errorf("expected overflow error, got %v", err)
+			}
+			if _, err := enc.DecodeUint64("#"); !errors.Is(err, ErrMalformedId) {
+				t.Errorf("expected invalid character error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncoding_Bytes(t *testing.T) {
+	for _, enc := range encodings {
+		for _, size := range []int{1, 12, 16, 20} {
+			b := make([]byte, size)
+			for i := 0; i < 20; i++ {
+				rand.Read(b)
+				s := enc.EncodeBytes(b)
+				got, err := enc.DecodeBytes(s, size)
+				if err != nil || !bytes.Equal(got, b) {
+					t.Fatalf("%s: DecodeBytes(%q) = %x, %v; want %x", enc.Name(), s, got, err, b)
+				}
+			}
+		}
+	}
+}
+
+func TestBase32Crockford_Aliases(t *testing.T) {
+	want, _ := Base32Crockford.DecodeUint64("10ZZ")
+	for _, s := range []string{"IOzz", "lozz", "Lozz", "i0Zz"} {
+		if got, err := Base32Crockford.DecodeUint64(s); err != nil || got != want {
+			t.Errorf("DecodeUint64(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	if _, err := Base32Crockford.DecodeUint64("U"); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("expected U to be rejected, got %v", err)
+	}
+}
+
+func TestNewEncoding(t *testing.T) {
+	for _, alphabet := range []string{"", "a", "aba", "ab\xff"} {
+		if _, err := NewEncoding("custom", alphabet); err == nil {
+			t.Errorf("NewEncoding(%q): expected error", alphabet)
+		}
+	}
+}
+
+func TestEncodeSnowflakeId(t *testing.T) {
+	ids := []int64{0, 1, GenSnowflakeId(), GenSnowflakeId(), math.MaxInt64}
+	for _, enc := range encodings {
+		var prev string
+		for _, id := range ids {
+			s, err := EncodeSnowflakeId(id, enc)
+			if err != nil || len(s) != enc.Width(63) || s < prev {
+				t.Fatalf("%s: EncodeSnowflakeId(%d) = %q, %v", enc.Name(), id, s, err)
+			}
+			if got, err := DecodeSnowflakeId(s, enc); err != nil || got != id {
+				t.Fatalf("%s: DecodeSnowflakeId(%q) = %d, %v; want %d", enc.Name(), s, got, err, id)
+			}
+			prev = s
+		}
+		if _, err := DecodeSnowflakeId(enc.EncodeUint64(math.MaxUint64), enc); !errors.Is(err, ErrMalformedId) {
+			t.Errorf("%s: expected int64 overflow error, got %v", enc.Name(), err)
+		}
+	}
+	if _, err := EncodeSnowflakeId(-1, Base62); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("expected error for negative id, got %v", err)
+	}
+}
+
+func TestUUIdULIdConversion(t *testing.T) {
+	for _, ver := range []string{"v4", "v7"} {
+		id, _ := GenUUId(ver, "")
+		u, err := UUIdToULId(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := ULIdToUUId(u)
+		if err != nil || back != id {
+			t.Errorf("round trip %s -> %s -> %s, %v", id, u, back, err)
+		}
+	}
+
+	// v7 与 ULID 都以 48 位毫秒时间开头，转换后时间一致
+	v7, _ := GenUUId("v7", "")
+	u, _ := UUIdToULId(v7)
+	uuidTs, _ := UUIdTimestamp(v7)
+	if parsed, _ := ParseULId(u); !parsed.Timestamp.Equal(uuidTs) {
+		t.Errorf("ulid time %v differs from uuid time %v", parsed.Timestamp, uuidTs)
+	}
+}
@@ -0,0 +1,144 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownScheme 未注册的 ID 方案
+	ErrUnknownScheme = errors.New("idgen: unknown scheme")
+	// ErrNoTimestamp ID 方案不包含时间信息
+	ErrNoTimestamp = errors.New("idgen: id has no timestamp")
+	// ErrMalformedId ID 格式不合法
+	ErrMalformedId = errors.New("idgen: malformed id")
+)
+
+// IdGenerator 各 ID 方案的统一接口，业务通过 Lookup 按配置选择方案
+type IdGenerator interface {
+	// Scheme 方案名称
+	Scheme() string
+	// Next 生成一个 ID
+	Next() (string, error)
+	// NextN 生成 n 个 ID
+	NextN(n int) ([]string, error)
+	// Parse 解析 ID，格式不合法时返回 ErrMalformedId
+	Parse(id string) (ParsedId, error)
+	// Validate 校验 ID 格式
+	Validate(id string) error
+	// Timestamp 返回 ID 内嵌的时间，不包含时间的方案返回 ErrNoTimestamp
+	Timestamp(id string) (time.Time, error)
+}
+
+// ParsedId 解析后的 ID
+type ParsedId struct {
+	Scheme    string    // 方案名称
+	Value     string    // 规范化的字符串形式
+	Bytes     []byte    // 二进制形式，没有二进制形式的方案为 nil
+	Timestamp time.Time // 内嵌时间，不包含时间的方案为零值
+}
+
+var registry = struct {
+	sync.RWMutex
+	generators map[string]IdGenerator
+}{generators: make(map[string]IdGenerator)}
+
+// Register 按名称注册 ID 方案，同名方案会被替换
+func Register(name string, generator IdGenerator) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.generators[name] = generator
+}
+
+// Lookup 按名称查找 ID 方案
+func Lookup(name string) (IdGenerator, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	generator, ok := registry.generators[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, name)
+	}
+	return generator, nil
+}
+
+// Schemes 返回已注册的方案名称
+func Schemes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.generators))
+	for name := range registry.generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nextN 逐个调用 next 生成 n 个 ID
+func nextN(n int, next func() (string, error)) ([]string, error) {
+	ids := make([]string, n)
+	for i := range ids {
+		id, err := next()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// ParseError ID 格式不合法的具体原因，可通过 errors.Is(err, ErrMalformedId) 判断
+type ParseError struct {
+	Scheme string // 方案名称
+	Id     string // 原始输入
+	Reason string // 不合法的原因
+	Err    error  // 底层错误，可能为 nil
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%v: %s %q", ErrMalformedId, e.Scheme, e.Id)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrMalformedId
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// malformed 包装为 *ParseError，原因取自 err
+func malformed(scheme string, id string, err error) error {
+	parseErr := &ParseError{Scheme: scheme, Id: id, Err: err}
+	if err != nil {
+		parseErr.Reason = err.Error()
+	}
+	return parseErr
+}
+
+// malformedf 包装为 *ParseError，原因按格式生成
+func malformedf(scheme string, id string, format string, args ...any) error {
+	return &ParseError{Scheme: scheme, Id: id, Reason: fmt.Sprintf(format, args...)}
+}
+
+func init() {
+	uuidV4, _ := NewUUIdGenerator("v4")
+	Register("uuid", uuidV4)
+	for _, ver := range []string{"v1", "v4", "v6", "v7"} {
+		generator, _ := NewUUIdGenerator(ver)
+		Register("uuid"+ver, generator)
+	}
+	Register("ulid", defaultULIdGenerator)
+	Register("ksuid", ksuidGenerator{})
+	Register("xid", xidGenerator{})
+	Register("nanoid", defaultNanoIdGenerator)
+	Register("snowflake", NewSnowflakeIdGenerator(nil))
+}
@@ -0,0 +1,154 @@
+package idgen
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisteredGenerators(t *testing.T) {
+	withTimestamp := map[string]bool{
+		"uuid": false, "uuidv1": true, "uuidv4": false, "uuidv6": true, "uuidv7": true, "ulid": true,
+		"ksuid": true, "xid": true, "nanoid": false, "snowflake": true,
+	}
+
+	for name, hasTimestamp := range withTimestamp {
+		t.Run(name, func(t *testing.T) {
+			generator, err := Lookup(name)
+			if err != nil {
+				t.Fatalf("Lookup(%q): %v", name, err)
+			}
+
+			ids, err := generator.NextN(100)
+			if err != nil {
+				t.Fatalf("NextN: %v", err)
+			}
+			seen := make(map[string]struct{}, len(ids))
+			for _, id := range ids {
+				if _, ok := seen[id]; ok {
+					t.Fatalf("duplicate id %s", id)
+				}
+				seen[id] = struct{}{}
+				if err = generator.Validate(id); err != nil {
+					t.Fatalf("Validate(%q): %v", id, err)
+				}
+			}
+
+			id, err := generator.Next()
+			if err != nil {
+				t.Fatalf("Next: %v", err)
+			}
+			parsed, err := generator.Parse(id)
+			if err != nil || parsed.Value != id || parsed.Scheme != generator.Scheme() {
+				t.Fatalf("Parse(%q) = %+v, %v", id, parsed, err)
+			}
+
+			ts, err := generator.Timestamp(id)
+			if hasTimestamp {
+				if err != nil || time.Since(ts) > time.Minute || time.Until(ts) > time.Minute {
+					t.Errorf("Timestamp(%q) = %v, %v", id, ts, err)
+				}
+			} else if !errors.Is(err, ErrNoTimestamp) {
+				t.Errorf("Timestamp(%q): expected ErrNoTimestamp, got %v", id, err)
+			}
+
+			if err = generator.Validate("not an id!"); !errors.Is(err, ErrMalformedId) {
+				t.Errorf("Validate(garbage): expected ErrMalformedId, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if _, err := Lookup("missing"); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("expected ErrUnknownScheme, got %v", err)
+	}
+
+	options := NewOptions(11)
+	generator, err := NewGenerator(options)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	Register("orders", NewSnowflakeIdGenerator(generator))
+	defer func() {
+		registry.Lock()
+		delete(registry.generators, "orders")
+		registry.Unlock()
+	}()
+
+	orders, err := Lookup("orders")
+	if err != nil {
+		t.Fatalf("Lookup(orders): %v", err)
+	}
+	id, _ := orders.Next()
+	if parsed, err := orders.Parse(id); err != nil || parsed.Timestamp.IsZero() {
+		t.Errorf("Parse(%q) = %+v, %v", id, parsed, err)
+	}
+
+	found := false
+	for _, name := range Schemes() {
+		found = found || name == "orders"
+	}
+	if !found {
+		t.Errorf("Schemes() = %v; missing orders", Schemes())
+	}
+
+	if _, err = NewUUIdGenerator("v3"); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("expected ErrUnknownScheme for uuid v3, got %v", err)
+	}
+	uuidV4, _ := Lookup("uuidv4")
+	v1, _ := GenUUId("v1", "")
+	if err = uuidV4.Validate(v1); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("expected version mismatch to be malformed, got %v", err)
+	}
+}
+
+func TestParseFunctions(t *testing.T) {
+	v7, _ := GenUUId("v7", "")
+	if parsed, err := ParseUUId(v7, 7); err != nil || parsed.Version != 7 || parsed.Timestamp.IsZero() {
+		t.Errorf("ParseUUId(v7) = %+v, %v", parsed, err)
+	}
+
+	x, _ := ParseXId(GenXId())
+	if len(x.Machine) != 3 || x.Timestamp.IsZero() {
+		t.Errorf("ParseXId = %+v", x)
+	}
+	if k, err := ParseKsuId(GenKsuId()); err != nil || len(k.Payload) != 16 {
+		t.Errorf("ParseKsuId = %+v, %v", k, err)
+	}
+	if u, err := ParseULId(GenULId()); err != nil || len(u.Entropy) != 10 {
+		t.Errorf("ParseULId = %+v, %v", u, err)
+	}
+	if n, err := ParseNanoId("abc123", "abc123", 6); err != nil || n.Bits < 15 || n.Bits > 16 {
+		t.Errorf("ParseNanoId = %+v, %v", n, err)
+	}
+	if _, err := ParseSnowflakeId(strconv.FormatInt(GenSnowflakeId(), 10), nil); err != nil {
+		t.Errorf("ParseSnowflakeId: %v", err)
+	}
+
+	invalid := []struct {
+		name   string
+		parse  func() error
+		reason string
+	}{
+		{"uuid version", func() error { _, err := ParseUUId(v7, 4); return err }, "version 7, want [4]"},
+		{"uuid variant", func() error { _, err := ParseUUId("00000000-0000-4000-0000-000000000000"); return err }, "variant Reserved"},
+		{"ulid length", func() error { _, err := ParseULId("01ARZ3NDEKTSV4RRFFQ69G5FA"); return err }, "length 25, want 26"},
+		{"ulid overflow", func() error { _, err := ParseULId("81ARZ3NDEKTSV4RRFFQ69G5FAV"); return err }, "overflow"},
+		{"ksuid character", func() error { _, err := ParseKsuId("0ujtsYcgvSTl8PAuAdqWYSMnLO_"); return err }, "position 26"},
+		{"xid character", func() error { _, err := ParseXId("9m4e2mr0ui3e8a215n4W"); return err }, "position 19"},
+		{"nanoid alphabet", func() error { _, err := ParseNanoId("abcx", "abc", 4); return err }, "position 3"},
+		{"nanoid length", func() error { _, err := ParseNanoId("abc", "abc", 4); return err }, "length 3, want 4"},
+		{"snowflake negative", func() error { _, err := ParseSnowflakeId("-5", nil); return err }, "negative"},
+		{"snowflake decimal", func() error { _, err := ParseSnowflakeId("12ab", nil); return err }, "invalid syntax"},
+	}
+	for _, tc := range invalid {
+		err := tc.parse()
+		var parseErr *ParseError
+		if !errors.Is(err, ErrMalformedId) || !errors.As(err, &parseErr) || !strings.Contains(parseErr.Reason, tc.reason) {
+			t.Errorf("%s: got %v, want reason containing %q", tc.name, err, tc.reason)
+		}
+	}
+}
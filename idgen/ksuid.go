@@ -1,9 +1,80 @@
 package idgen
 
-import "github.com/segmentio/ksuid"
+import (
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
 
 func GenKsuId() string {
 	id := ksuid.New()
 
 	return id.String()
 }
+
+// ksuidEncodedSize KSUID 字符串长度
+const ksuidEncodedSize = 27
+
+// ParsedKsuId 解析后的 KSUID
+type ParsedKsuId struct {
+	KSUID     ksuid.KSUID
+	Timestamp time.Time // 秒精度的内嵌时间
+	Payload   []byte    // 128 位随机负载
+}
+
+// ParseKsuId 解析并校验 KSUID，只接受 27 位 base62，不合法时返回 *ParseError
+func ParseKsuId(id string) (ParsedKsuId, error) {
+	if len(id) != ksuidEncodedSize {
+		return ParsedKsuId{}, malformedf("ksuid", id, "length %d, want %d", len(id), ksuidEncodedSize)
+	}
+	if i := strings.IndexFunc(id, func(r rune) bool { return !isBase62(r) }); i >= 0 {
+		return ParsedKsuId{}, malformedf("ksuid", id, "invalid character %q at position %d", id[i], i)
+	}
+	k, err := ksuid.Parse(id)
+	if err != nil {
+		return ParsedKsuId{}, malformed("ksuid", id, err)
+	}
+	return ParsedKsuId{KSUID: k, Timestamp: k.Time(), Payload: k.Payload()}, nil
+}
+
+func isBase62(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z'
+}
+
+// ksuidGenerator KSUID 方案
+type ksuidGenerator struct{}
+
+func (ksuidGenerator) Scheme() string {
+	return "ksuid"
+}
+
+func (ksuidGenerator) Next() (string, error) {
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+func (g ksuidGenerator) NextN(n int) ([]string, error) {
+	return nextN(n, g.Next)
+}
+
+func (g ksuidGenerator) Parse(id string) (ParsedId, error) {
+	parsed, err := ParseKsuId(id)
+	if err != nil {
+		return ParsedId{}, err
+	}
+	return ParsedId{Scheme: g.Scheme(), Value: parsed.KSUID.String(), Bytes: parsed.KSUID.Bytes(), Timestamp: parsed.Timestamp}, nil
+}
+
+func (g ksuidGenerator) Validate(id string) error {
+	_, err := g.Parse(id)
+	return err
+}
+
+func (g ksuidGenerator) Timestamp(id string) (time.Time, error) {
+	parsed, err := g.Parse(id)
+	return parsed.Timestamp, err
+}
@@ -1,13 +1,216 @@
 package idgen
 
-import gonanoid "github.com/matoous/go-nanoid/v2"
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// NanoID 字母表预设
+const (
+	NanoIdAlphabetURLSafe      = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	NanoIdAlphabetAlphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	NanoIdAlphabetLowercase    = "abcdefghijklmnopqrstuvwxyz"
+	NanoIdAlphabetNumbers      = "0123456789"
+	NanoIdAlphabetNoLookalikes = "346789ABCDEFGHJKLMNPQRTUVWXYabcdefghijkmnpqrtwxyz" // 去掉 1lI 0Oo 2Z 5S uvV 等易混字符
+)
+
+// 默认字母表和长度，与 gonanoid.New() 一致
+const (
+	nanoIdDefaultAlphabet = NanoIdAlphabetURLSafe
+	nanoIdDefaultSize     = 21
+)
+
+// defaultNanoIdGenerator 默认字母表和长度的生成器
+var defaultNanoIdGenerator, _ = NewNanoIDGenerator(nanoIdDefaultAlphabet, nanoIdDefaultSize)
+
+// GenNanoId 生成 NanoID，alphabet 为空时使用默认字母表，size <= 0 时使用默认长度。
+// 每次调用都会校验字母表，频繁调用时使用 NewNanoIDGenerator 创建的生成器
 func GenNanoId(alphabet string, size int) (id string, err error) {
 	if alphabet == "" {
 		if size <= 0 {
-			return gonanoid.New()
+			return defaultNanoIdGenerator.Next()
 		}
 		return gonanoid.New(size)
 	}
 	return gonanoid.Generate(alphabet, size)
 }
+
+// NanoIDGenerator 字母表和长度固定的 NanoID 生成器，创建时完成校验，并发安全
+type NanoIDGenerator struct {
+	alphabet []rune
+	size     int
+	mask     int       // 取随机字节低位的掩码，2^k-1 >= 字母表大小-1
+	step     int       // 每批读取的随机字节数
+	buffers  sync.Pool // 随机字节缓冲，*[]byte
+}
+
+// NewNanoIDGenerator 创建生成器，字母表需为 2-256 个不重复的字符，size 需大于 0
+func NewNanoIDGenerator(alphabet string, size int) (*NanoIDGenerator, error) {
+	chars := []rune(alphabet)
+	if len(chars) < 2 || len(chars) > 256 {
+		return nil, fmt.Errorf("idgen: nanoid alphabet size %d out of range [2, 256]", len(chars))
+	}
+	seen := make(map[rune]bool, len(chars))
+	for _, r := range chars {
+		if seen[r] {
+			return nil, fmt.Errorf("idgen: nanoid alphabet contains duplicate %q", r)
+		}
+		seen[r] = true
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("idgen: nanoid size %d must be positive", size)
+	}
+
+	mask := 1
+	for mask < len(chars)-1 {
+		mask = mask<<1 | 1
+	}
+	// 与 nanoid 相同，按被拒绝的概率多取 60% 的字节，尽量一批完成
+	step := int(math.Ceil(1.6 * float64(mask*size) / float64(len(chars))))
+	g := &NanoIDGenerator{alphabet: chars, size: size, mask: mask, step: step}
+	g.buffers.New = func() any {
+		buf := make([]byte, step)
+		return &buf
+	}
+	return g, nil
+}
+
+// Alphabet 字母表
+func (g *NanoIDGenerator) Alphabet() string {
+	return string(g.alphabet)
+}
+
+// Size ID 长度（字符数）
+func (g *NanoIDGenerator) Size() int {
+	return g.size
+}
+
+func (*NanoIDGenerator) Scheme() string {
+	return "nanoid"
+}
+
+func (g *NanoIDGenerator) Next() (string, error) {
+	buf := g.buffers.Get().(*[]byte)
+	defer g.buffers.Put(buf)
+
+	id := make([]rune, 0, g.size)
+	for {
+		if _, err := rand.Read(*buf); err != nil {
+			return "", err
+		}
+		for _, b := range *buf {
+			if index := int(b) & g.mask; index < len(g.alphabet) {
+				id = append(id, g.alphabet[index])
+				if len(id) == g.size {
+					return string(id), nil
+				}
+			}
+		}
+	}
+}
+
+func (g *NanoIDGenerator) NextN(n int) ([]string, error) {
+	return nextN(n, g.Next)
+}
+
+func (g *NanoIDGenerator) Parse(id string) (ParsedId, error) {
+	parsed, err := ParseNanoId(id, string(g.alphabet), g.size)
+	if err != nil {
+		return ParsedId{}, err
+	}
+	return ParsedId{Scheme: g.Scheme(), Value: parsed.Value}, nil
+}
+
+func (g *NanoIDGenerator) Validate(id string) error {
+	_, err := g.Parse(id)
+	return err
+}
+
+func (g *NanoIDGenerator) Timestamp(id string) (time.Time, error) {
+	if err := g.Validate(id); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, ErrNoTimestamp
+}
+
+// CollisionProbability 见 NanoIdCollisionProbability
+func (g *NanoIDGenerator) CollisionProbability(count float64) float64 {
+	return nanoIdCollisionProbability(len(g.alphabet), g.size, count)
+}
+
+// TimeToCollision 见 NanoIdTimeToCollision
+func (g *NanoIDGenerator) TimeToCollision(perSecond float64, probability float64) time.Duration {
+	return nanoIdTimeToCollision(len(g.alphabet), g.size, perSecond, probability)
+}
+
+// NanoIdCollisionProbability 按生日问题估算生成 count 个 ID 后至少出现一次碰撞的概率
+func NanoIdCollisionProbability(alphabet string, size int, count float64) float64 {
+	return nanoIdCollisionProbability(utf8.RuneCountInString(alphabet), size, count)
+}
+
+// NanoIdTimeToCollision 估算以每秒 perSecond 个的速率生成时，碰撞概率达到 probability（如 0.01）所需的时间，
+// 超出 time.Duration 的范围时返回最大值
+func NanoIdTimeToCollision(alphabet string, size int, perSecond float64, probability float64) time.Duration {
+	return nanoIdTimeToCollision(utf8.RuneCountInString(alphabet), size, perSecond, probability)
+}
+
+// 空间大小 N = alphabetSize^size 可能远超 float64，统一在对数域计算：p ≈ 1 - e^(-n²/2N)
+func nanoIdCollisionProbability(alphabetSize int, size int, count float64) float64 {
+	if count < 2 {
+		return 0
+	}
+	logSpace := float64(size) * math.Log(float64(alphabetSize))
+	return -math.Expm1(-math.Exp(2*math.Log(count) - math.Ln2 - logSpace))
+}
+
+// n = sqrt(2N * ln(1/(1-p)))
+func nanoIdTimeToCollision(alphabetSize int, size int, perSecond float64, probability float64) time.Duration {
+	if probability <= 0 || perSecond <= 0 {
+		return math.MaxInt64
+	}
+	if probability >= 1 {
+		probability = math.Nextafter(1, 0)
+	}
+	logSpace := float64(size) * math.Log(float64(alphabetSize))
+	logCount := (math.Ln2 + logSpace + math.Log(-math.Log1p(-probability))) / 2
+	seconds := math.Exp(logCount) / perSecond
+	if seconds >= float64(math.MaxInt64)/float64(time.Second) {
+		return math.MaxInt64
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ParsedNanoId 解析后的 NanoID，NanoID 没有内部结构，只记录校验结果
+type ParsedNanoId struct {
+	Value string
+	Bits  float64 // 随机位数，等于 长度 * log2(字母表大小)
+}
+
+// ParseNanoId 按字母表和长度校验 NanoID，alphabet 为空或 size <= 0 时使用默认值，不合法时返回 *ParseError
+func ParseNanoId(id string, alphabet string, size int) (ParsedNanoId, error) {
+	if alphabet == "" {
+		alphabet = nanoIdDefaultAlphabet
+	}
+	if size <= 0 {
+		size = nanoIdDefaultSize
+	}
+	if n := utf8.RuneCountInString(id); n != size {
+		return ParsedNanoId{}, malformedf("nanoid", id, "length %d, want %d", n, size)
+	}
+	position := 0
+	for _, r := range id {
+		if !strings.ContainsRune(alphabet, r) {
+			return ParsedNanoId{}, malformedf("nanoid", id, "character %q at position %d is not in alphabet", r, position)
+		}
+		position++
+	}
+	bits := float64(size) * math.Log2(float64(utf8.RuneCountInString(alphabet)))
+	return ParsedNanoId{Value: id, Bits: bits}, nil
+}
@@ -0,0 +1,93 @@
+package idgen
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestNanoIDGenerator_Presets(t *testing.T) {
+	presets := []string{
+		NanoIdAlphabetURLSafe, NanoIdAlphabetAlphanumeric, NanoIdAlphabetLowercase,
+		NanoIdAlphabetNumbers, NanoIdAlphabetNoLookalikes, "αβγδεζ",
+	}
+	for _, alphabet := range presets {
+		g, err := NewNanoIDGenerator(alphabet, 12)
+		if err != nil {
+			t.Fatalf("NewNanoIDGenerator(%q): %v", alphabet, err)
+		}
+		counts := make(map[rune]int)
+		for i := 0; i < 2000; i++ {
+			id, err := g.Next()
+			if err != nil || utf8.RuneCountInString(id) != 12 || g.Validate(id) != nil {
+				t.Fatalf("Next() = %q, %v", id, err)
+			}
+			for _, r := range id {
+				counts[r]++
+			}
+		}
+		// 每个字符都应出现，且分布大致均匀
+		expected := 2000 * 12 / float64(utf8.RuneCountInString(alphabet))
+		for _, r := range alphabet {
+			if c := float64(counts[r]); c < expected*0.7 || c > expected*1.3 {
+				t.Errorf("alphabet %q: %q appeared %v times, expected about %v", alphabet, r, c, expected)
+			}
+		}
+	}
+
+	if strings.ContainsAny(NanoIdAlphabetNoLookalikes, "1lI0Oo") {
+		t.Errorf("no-lookalikes alphabet contains lookalike characters")
+	}
+}
+
+func TestNewNanoIDGenerator_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		alphabet string
+		size     int
+	}{{"a", 10}, {"abca", 10}, {"abc", 0}} {
+		if _, err := NewNanoIDGenerator(tc.alphabet, tc.size); err == nil {
+			t.Errorf("NewNanoIDGenerator(%q, %d): expected error", tc.alphabet, tc.size)
+		}
+	}
+}
+
+func TestNanoIdCollision(t *testing.T) {
+	// 1e8 的空间内，约 1418 个 ID 时碰撞概率达到 1%
+	ttc := NanoIdTimeToCollision(NanoIdAlphabetNumbers, 8, 1, 0.01)
+	if ttc < 1410*time.Second || ttc > 1425*time.Second {
+		t.Errorf("NanoIdTimeToCollision = %v, want about 1418s", ttc)
+	}
+	if p := NanoIdCollisionProbability(NanoIdAlphabetNumbers, 8, ttc.Seconds()); math.Abs(p-0.01) > 1e-6 {
+		t.Errorf("NanoIdCollisionProbability = %v, want 0.01", p)
+	}
+
+	g, _ := NewNanoIDGenerator(NanoIdAlphabetURLSafe, 21)
+	if p := g.CollisionProbability(1e9); p <= 0 || p > 1e-15 {
+		t.Errorf("CollisionProbability(1e9) = %v", p)
+	}
+	if ttc := g.TimeToCollision(1000, 0.01); ttc != math.MaxInt64 {
+		t.Errorf("TimeToCollision should saturate, got %v", ttc)
+	}
+	if p := NanoIdCollisionProbability(NanoIdAlphabetNumbers, 2, 1000); p < 0.99 {
+		t.Errorf("exhausted space should almost surely collide, got %v", p)
+	}
+}
+
+func BenchmarkNanoIDGenerator(b *testing.B) {
+	g, _ := NewNanoIDGenerator(NanoIdAlphabetURLSafe, 21)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = g.Next()
+		}
+	})
+}
+
+func BenchmarkGenNanoIdCustomAlphabet(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = GenNanoId(NanoIdAlphabetNoLookalikes, 21)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrBlocklistExhausted 所有候选编码都命中屏蔽词，需要调整字母表或屏蔽词
+var ErrBlocklistExhausted = errors.New("idgen: every candidate encoding hits the blocklist")
+
+// obfuscatorDefaultAlphabet 默认字母表，URL 安全
+const obfuscatorDefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// obfuscatorDefaultBlocklist 默认屏蔽词，匹配时不区分大小写
+var obfuscatorDefaultBlocklist = []string{
+	"anal", "arse", "ass", "bitch", "cock", "cunt", "dick", "fag", "fuck", "nazi",
+	"piss", "porn", "rape", "sex", "shit", "slut", "tits", "twat", "wank", "whore",
+}
+
+// ObfuscatorOption Obfuscator 的配置项
+type ObfuscatorOption func(*Obfuscator)
+
+// WithObfuscatorAlphabet 替换字母表，需为至少 3 个不重复的 ASCII 字符
+func WithObfuscatorAlphabet(alphabet string) ObfuscatorOption {
+	return func(o *Obfuscator) {
+		o.alphabet = []byte(alphabet)
+	}
+}
+
+// WithObfuscatorMinLength 编码结果的最小长度，取值 [0, 255]
+func WithObfuscatorMinLength(minLength int) ObfuscatorOption {
+	return func(o *Obfuscator) {
+		o.minLength = minLength
+	}
+}
+
+// WithObfuscatorSalt 使用盐打乱字母表，不同的盐得到互不兼容的编码
+func WithObfuscatorSalt(salt string) ObfuscatorOption {
+	return func(o *Obfuscator) {
+		o.salt = salt
+	}
+}
+
+// WithObfuscatorBlocklist 替换屏蔽词，编码结果中出现屏蔽词时会改用下一个候选编码
+func WithObfuscatorBlocklist(words ...string) ObfuscatorOption {
+	return func(o *Obfuscator) {
+		o.blocklist = words
+	}
+}
+
+// Obfuscator 将非负 int64（或一组 int64）可逆地编码为短小且不连续的字符串，算法与 Sqids 一致，另加盐打乱字母表。
+// 编码只用于隐藏 ID 的时间和数量信息，不是加密，不能替代权限校验
+type Obfuscator struct {
+	alphabet  []byte
+	minLength int
+	salt      string
+	blocklist []string
+}
+
+// NewObfuscator 创建编码器，配置不合法时返回错误
+func NewObfuscator(opts ...ObfuscatorOption) (*Obfuscator, error) {
+	o := &Obfuscator{alphabet: []byte(obfuscatorDefaultAlphabet), blocklist: obfuscatorDefaultBlocklist}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if len(o.alphabet) < 3 {
+		return nil, fmt.Errorf("idgen: obfuscator alphabet must contain at least 3 characters")
+	}
+	var seen [256]bool
+	for _, c := range o.alphabet {
+		if c >= 0x80 {
+			return nil, fmt.Errorf("idgen: obfuscator alphabet contains non-ASCII byte %#x", c)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("idgen: obfuscator alphabet contains duplicate %q", c)
+		}
+		seen[c] = true
+	}
+	if o.minLength < 0 || o.minLength > 255 {
+		return nil, fmt.Errorf("idgen: obfuscator min length %d out of range [0, 255]", o.minLength)
+	}
+
+	// 屏蔽词只保留能由字母表拼出的部分
+	lowerAlphabet := strings.ToLower(string(o.alphabet))
+	blocklist := make([]string, 0, len(o.blocklist))
+	for _, word := range o.blocklist {
+		word = strings.ToLower(word)
+		if len(word) >= 3 && strings.Trim(word, lowerAlphabet) == "" {
+			blocklist = append(blocklist, word)
+		}
+	}
+	o.blocklist = blocklist
+
+	o.alphabet = slices.Clone(o.alphabet)
+	if o.salt != "" {
+		saltShuffle(o.alphabet, o.salt)
+	}
+	shuffle(o.alphabet)
+	return o, nil
+}
+
+// Encode 编码单个 ID
+func (o *Obfuscator) Encode(id int64) (string, error) {
+	return o.EncodeList([]int64{id})
+}
+
+// EncodeList 将一组 ID 编码为一个字符串，顺序和重复值都会保留
+func (o *Obfuscator) EncodeList(ids []int64) (string, error) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+	numbers := make([]uint64, len(ids))
+	for i, id := range ids {
+		if id < 0 {
+			return "", fmt.Errorf("idgen: obfuscator cannot encode negative id %d", id)
+		}
+		numbers[i] = uint64(id)
+	}
+	return o.encode(numbers, 0)
+}
+
+func (o *Obfuscator) encode(numbers []uint64, increment int) (string, error) {
+	size := len(o.alphabet)
+	if increment > size {
+		return "", ErrBlocklistExhausted
+	}
+
+	offset := len(numbers)
+	for i, n := range numbers {
+		offset += int(o.alphabet[n%uint64(size)]) + i
+	}
+	offset = (offset + increment) % size
+
+	alphabet := append(slices.Clone(o.alphabet[offset:]), o.alphabet[:offset]...)
+	prefix := alphabet[0]
+	slices.Reverse(alphabet)
+
+	id := []byte{prefix}
+	for i, n := range numbers {
+		id = appendDigits(id, n, alphabet[1:])
+		if i < len(numbers)-1 {
+			id = append(id, alphabet[0])
+			shuffle(alphabet)
+		}
+	}
+
+	if len(id) < o.minLength {
+		id = append(id, alphabet[0])
+		for len(id) < o.minLength {
+			shuffle(alphabet)
+			id = append(id, alphabet[:min(o.minLength-len(id), size)]...)
+		}
+	}
+
+	if o.blocked(string(id)) {
+		return o.encode(numbers, increment+1)
+	}
+	return string(id), nil
+}
+
+// Decode 解码 Encode 的结果，字符串被篡改或包含多个 ID 时返回 *ParseError
+func (o *Obfuscator) Decode(s string) (int64, error) {
+	ids, err := o.DecodeList(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) != 1 {
+		return 0, malformedf("obfuscated", s, "contains %d ids, want 1", len(ids))
+	}
+	return ids[0], nil
+}
+
+// DecodeList 解码 EncodeList 的结果。解码后会重新编码比对，只接受编码器自己产生的规范形式，
+// 因此被篡改、补位字符被修改或命中屏蔽词的字符串都会被拒绝
+func (o *Obfuscator) DecodeList(s string) ([]int64, error) {
+	if s == "" {
+		return nil, malformedf("obfuscated", s, "empty")
+	}
+	if i := strings.IndexFunc(s, func(r rune) bool { return !slices.Contains(o.alphabet, byte(r)) || r >= 0x80 }); i >= 0 {
+		return nil, malformedf("obfuscated", s, "invalid character %q at position %d", s[i], i)
+	}
+
+	offset := slices.Index(o.alphabet, s[0])
+	alphabet := append(slices.Clone(o.alphabet[offset:]), o.alphabet[:offset]...)
+	slices.Reverse(alphabet)
+
+	var ids []int64
+	rest := s[1:]
+	for rest != "" {
+		chunk, next, found := strings.Cut(rest, string(alphabet[0]))
+		if chunk == "" {
+			// 剩余部分为补位字符
+			break
+		}
+		n, ok := parseDigits(chunk, alphabet[1:])
+		if !ok || n > 1<<63-1 {
+			return nil, malformedf("obfuscated", s, "value overflows int64")
+		}
+		ids = append(ids, int64(n))
+		if !found {
+			break
+		}
+		shuffle(alphabet)
+		rest = next
+	}
+	if len(ids) == 0 {
+		return nil, malformedf("obfuscated", s, "contains no id")
+	}
+
+	canonical, err := o.EncodeList(ids)
+	if err != nil || canonical != s {
+		return nil, malformedf("obfuscated", s, "not a canonical encoding")
+	}
+	return ids, nil
+}
+
+// blocked 判断编码结果是否包含屏蔽词：短词需完全相同，含数字的词只匹配首尾，其余匹配子串
+func (o *Obfuscator) blocked(id string) bool {
+	id = strings.ToLower(id)
+	for _, word := range o.blocklist {
+		if len(word) > len(id) {
+			continue
+		}
+		switch {
+		case len(id) <= 3 || len(word) <= 3:
+			if id == word {
+				return true
+			}
+		case strings.ContainsAny(word, "0123456789"):
+			if strings.HasPrefix(id, word) || strings.HasSuffix(id, word) {
+				return true
+			}
+		case strings.Contains(id, word):
+			return true
+		}
+	}
+	return false
+}
+
+// appendDigits 以 alphabet 为数字表追加 n 的表示，高位在前
+func appendDigits(dst []byte, n uint64, alphabet []byte) []byte {
+	var buf [64]byte
+	i := len(buf)
+	base := uint64(len(alphabet))
+	for {
+		i--
+		buf[i] = alphabet[n%base]
+		if n /= base; n == 0 {
+			break
+		}
+	}
+	return append(dst, buf[i:]...)
+}
+
+// parseDigits appendDigits 的逆运算，溢出 uint64 时返回 false
+func parseDigits(s string, alphabet []byte) (uint64, bool) {
+	base := uint64(len(alphabet))
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		digit := slices.Index(alphabet, s[i])
+		if digit < 0 || n > (1<<64-1-uint64(digit))/base {
+			return 0, false
+		}
+		n = n*base + uint64(digit)
+	}
+	return n, true
+}
+
+// shuffle Sqids 的确定性打乱
+func shuffle(alphabet []byte) {
+	size := len(alphabet)
+	for i, j := 0, size-1; j > 0; i, j = i+1, j-1 {
+		r := (i*j + int(alphabet[i]) + int(alphabet[j])) % size
+		alphabet[i], alphabet[r] = alphabet[r], alphabet[i]
+	}
+}
+
+// saltShuffle Hashids 的加盐确定性打乱
+func saltShuffle(alphabet []byte, salt string) {
+	for i, v, p := len(alphabet)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	}
+}
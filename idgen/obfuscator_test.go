@@ -0,0 +1,129 @@
+package idgen
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestObfuscator_RoundTrip(t *testing.T) {
+	o, err := NewObfuscator(WithObfuscatorMinLength(10), WithObfuscatorSalt("lotus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int64{0, 1, 2, 100, math.MaxInt64, GenSnowflakeId()}
+	for i := 0; i < 500; i++ {
+		ids = append(ids, rand.Int63()>>rand.Intn(63))
+	}
+	seen := make(map[string]int64)
+	for _, id := range ids {
+		s, err := o.Encode(id)
+		if err != nil || len(s) < 10 {
+			t.Fatalf("Encode(%d) = %q, %v", id, s, err)
+		}
+		if got, err := o.Decode(s); err != nil || got != id {
+			t.Fatalf("Decode(%q) = %d, %v; want %d", s, got, err, id)
+		}
+		if other, ok := seen[s]; ok && other != id {
+			t.Fatalf("%d and %d share encoding %q", id, other, s)
+		}
+		seen[s] = id
+	}
+
+	list := []int64{3, 3, 0, math.MaxInt64, 42}
+	s, err := o.EncodeList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := o.DecodeList(s); err != nil || !slices.Equal(got, list) {
+		t.Errorf("DecodeList(%q) = %v, %v; want %v", s, got, err, list)
+	}
+	if _, err := o.Decode(s); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("Decode of a list: expected ErrMalformedId, got %v", err)
+	}
+}
+
+func TestObfuscator_NonSequential(t *testing.T) {
+	o, _ := NewObfuscator()
+	a, _ := o.Encode(1000)
+	b, _ := o.Encode(1001)
+	if a == b || strings.HasPrefix(b, a[:len(a)-1]) {
+		t.Errorf("consecutive ids encode too similarly: %q, %q", a, b)
+	}
+
+	salted, _ := NewObfuscator(WithObfuscatorSalt("another"))
+	if c, _ := salted.Encode(1000); c == a {
+		t.Errorf("salt did not change the encoding: %q", c)
+	}
+}
+
+func TestObfuscator_Tampered(t *testing.T) {
+	o, _ := NewObfuscator(WithObfuscatorMinLength(8))
+	s, _ := o.Encode(123456789)
+
+	tampered := []string{"", s + "!", s[:len(s)-1], s[:3]}
+	for i := range s {
+		for _, c := range []byte{'a', 'Z', '7'} {
+			if c != s[i] {
+				tampered = append(tampered, s[:i]+string(c)+s[i+1:])
+			}
+		}
+	}
+	for _, bad := range tampered {
+		if got, err := o.Decode(bad); err == nil && got == 123456789 {
+			t.Errorf("tampered %q decoded to the original id", bad)
+		} else if err == nil {
+			// 篡改后恰好是另一个合法编码时必须是规范形式
+			if again, _ := o.Encode(got); again != bad {
+				t.Errorf("tampered %q decoded to %d without being canonical", bad, got)
+			}
+		}
+	}
+}
+
+func TestObfuscator_Blocklist(t *testing.T) {
+	o, _ := NewObfuscator(WithObfuscatorBlocklist())
+	s, _ := o.Encode(4572721)
+	blocked, _ := NewObfuscator(WithObfuscatorBlocklist(s))
+	other, err := blocked.Encode(4572721)
+	if err != nil || other == s {
+		t.Fatalf("Encode with blocklist = %q, %v; want something other than %q", other, err, s)
+	}
+	if got, err := blocked.Decode(other); err != nil || got != 4572721 {
+		t.Errorf("Decode(%q) = %d, %v", other, got, err)
+	}
+	if _, err := blocked.Decode(s); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("blocked encoding should be rejected, got %v", err)
+	}
+}
+
+func TestNewObfuscator_Invalid(t *testing.T) {
+	invalid := [][]ObfuscatorOption{
+		{WithObfuscatorAlphabet("ab")},
+		{WithObfuscatorAlphabet("abca")},
+		{WithObfuscatorAlphabet("abcé")},
+		{WithObfuscatorMinLength(256)},
+	}
+	for _, opts := range invalid {
+		if _, err := NewObfuscator(opts...); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+	if _, err := (&Obfuscator{}).Encode(-1); err == nil {
+		t.Errorf("expected error for negative id")
+	}
+}
+
+// 不加盐时与 Sqids 的编码结果一致
+func TestObfuscator_SqidsCompatible(t *testing.T) {
+	for minLength, want := range map[int]string{0: "86Rf07", 10: "86Rf07xd4z"} {
+		o, _ := NewObfuscator(WithObfuscatorBlocklist(), WithObfuscatorMinLength(minLength))
+		if s, err := o.EncodeList([]int64{1, 2, 3}); err != nil || s != want {
+			t.Errorf("EncodeList with min length %d = %q, %v; want %q", minLength, s, err, want)
+		}
+	}
+}
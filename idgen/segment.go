@@ -0,0 +1,221 @@
+package idgen
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// SegmentOption SegmentAllocator 的配置项
+type SegmentOption func(*SegmentAllocator)
+
+// WithSegmentStep 初始号段长度，默认 1000
+func WithSegmentStep(step int64) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.step = step
+	}
+}
+
+// WithSegmentStepRange 自适应调整号段长度的范围，默认 [初始长度, 初始长度 * 100]
+func WithSegmentStepRange(minStep, maxStep int64) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.minStep, a.maxStep = minStep, maxStep
+	}
+}
+
+// WithSegmentPrefetchRatio 当前号段消耗到该比例时异步预取下一个号段，默认 0.8
+func WithSegmentPrefetchRatio(ratio float64) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.prefetchRatio = ratio
+	}
+}
+
+// WithSegmentTargetDuration 期望每个号段的使用时长，默认 15 分钟。
+// 号段在该时长的一半内耗尽时长度加倍，超过两倍时长才耗尽时长度减半
+func WithSegmentTargetDuration(d time.Duration) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.targetDuration = d
+	}
+}
+
+// WithSegmentClock 替换时钟，测试中可注入 timeutil.FakeClock
+func WithSegmentClock(clock timeutil.Clock) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.clock = clock
+	}
+}
+
+// SegmentAllocator 号段发号器（Leaf-segment），从 SegmentStore 批量取号后在本地递增发放，
+// 同一发号器发出的 ID 严格递增且基本连续；多个发号器共享 tag 时 ID 唯一但只在各自内部递增。
+// 进程退出时未用完的号段会被跳过
+type SegmentAllocator struct {
+	store          SegmentStore
+	tag            string
+	step           int64
+	minStep        int64
+	maxStep        int64
+	prefetchRatio  float64
+	targetDuration time.Duration
+	clock          timeutil.Clock
+
+	mu        sync.Mutex
+	loaded    *sync.Cond // 号段加载结束时广播
+	loading   bool
+	current   Segment
+	next      int64     // current 中下一个发放的 ID
+	fetchedAt time.Time // current 的取号时间
+	buffer    *Segment  // 预取的下一个号段
+}
+
+// NewSegmentAllocator 创建号段发号器，首个号段在第一次发号时获取
+func NewSegmentAllocator(store SegmentStore, tag string, opts ...SegmentOption) (*SegmentAllocator, error) {
+	a := &SegmentAllocator{
+		store:          store,
+		tag:            tag,
+		step:           1000,
+		prefetchRatio:  0.8,
+		targetDuration: 15 * time.Minute,
+		clock:          timeutil.RealClock{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.minStep == 0 && a.maxStep == 0 {
+		a.minStep, a.maxStep = a.step, a.step*100
+	}
+
+	switch {
+	case store == nil:
+		return nil, fmt.Errorf("idgen: segment store is nil")
+	case a.step <= 0 || a.minStep <= 0 || a.minStep > a.step || a.step > a.maxStep:
+		return nil, fmt.Errorf("idgen: segment step %d must be within [%d, %d] and positive", a.step, a.minStep, a.maxStep)
+	case a.prefetchRatio <= 0 || a.prefetchRatio > 1:
+		return nil, fmt.Errorf("idgen: segment prefetch ratio %v out of range (0, 1]", a.prefetchRatio)
+	case a.targetDuration <= 0:
+		return nil, fmt.Errorf("idgen: segment target duration %v must be positive", a.targetDuration)
+	}
+	a.loaded = sync.NewCond(&a.mu)
+	return a, nil
+}
+
+// NextId 发放下一个 ID。当前号段耗尽且预取未完成时等待，预取失败时同步取号并返回存储的错误
+func (a *SegmentAllocator) NextId() (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		if a.next < a.current.End {
+			id := a.next
+			a.next++
+			if !a.loading && a.buffer == nil && float64(a.next-a.current.Start) >= a.prefetchRatio*float64(a.current.Len()) {
+				a.loading = true
+				go a.prefetch(a.adjustStep())
+			}
+			return id, nil
+		}
+
+		switch {
+		case a.buffer != nil:
+			a.use(*a.buffer)
+			a.buffer = nil
+		case a.loading:
+			a.loaded.Wait()
+		default:
+			// 首次取号，或预取失败后同步重试
+			a.loading = true
+			step := a.adjustStep()
+			a.mu.Unlock()
+			segment, err := a.store.Allocate(a.tag, step)
+			a.mu.Lock()
+			a.loading = false
+			a.loaded.Broadcast()
+			if err != nil {
+				return 0, err
+			}
+			a.use(segment)
+		}
+	}
+}
+
+// Step 当前的号段长度
+func (a *SegmentAllocator) Step() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.step
+}
+
+// prefetch 异步获取下一个号段，失败时由耗尽号段的调用方同步重试
+func (a *SegmentAllocator) prefetch(step int64) {
+	segment, err := a.store.Allocate(a.tag, step)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		a.buffer = &segment
+	}
+	a.loading = false
+	a.loaded.Broadcast()
+}
+
+func (a *SegmentAllocator) use(segment Segment) {
+	a.current = segment
+	a.next = segment.Start
+	a.fetchedAt = a.clock.Now()
+}
+
+// adjustStep 按当前号段的消耗速度调整下一个号段的长度，调用方需持有锁
+func (a *SegmentAllocator) adjustStep() int64 {
+	if a.current.Len() == 0 {
+		return a.step
+	}
+	elapsed := a.clock.Now().Sub(a.fetchedAt)
+	switch {
+	case elapsed < a.targetDuration/2 && a.step*2 <= a.maxStep:
+		a.step *= 2
+	case elapsed > a.targetDuration*2 && a.step/2 >= a.minStep:
+		a.step /= 2
+	}
+	return a.step
+}
+
+func (a *SegmentAllocator) Scheme() string {
+	return "segment"
+}
+
+func (a *SegmentAllocator) Next() (string, error) {
+	id, err := a.NextId()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (a *SegmentAllocator) NextN(n int) ([]string, error) {
+	return nextN(n, a.Next)
+}
+
+func (a *SegmentAllocator) Parse(id string) (ParsedId, error) {
+	value, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return ParsedId{}, malformed(a.Scheme(), id, err)
+	}
+	if value <= 0 {
+		return ParsedId{}, malformedf(a.Scheme(), id, "id must be positive")
+	}
+	return ParsedId{Scheme: a.Scheme(), Value: id}, nil
+}
+
+func (a *SegmentAllocator) Validate(id string) error {
+	_, err := a.Parse(id)
+	return err
+}
+
+func (a *SegmentAllocator) Timestamp(id string) (time.Time, error) {
+	if err := a.Validate(id); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, ErrNoTimestamp
+}
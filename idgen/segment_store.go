@@ -0,0 +1,125 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dhlanshan/lotus/internal/fileutil"
+)
+
+// Segment 号段，包含 [Start, End) 内的 ID
+type Segment struct {
+	Start int64
+	End   int64
+}
+
+// Len 号段内的 ID 数量
+func (s Segment) Len() int64 {
+	return s.End - s.Start
+}
+
+// SegmentStore 号段存储，按业务标签分配号段。同一 tag 先后分配的号段互不重叠且递增，ID 从 1 开始
+type SegmentStore interface {
+	// Allocate 为 tag 分配 step 个连续的 ID
+	Allocate(tag string, step int64) (Segment, error)
+}
+
+// allocate 在 next 之后分配号段，返回号段和新的 next
+func allocate(tag string, next int64, step int64) (Segment, error) {
+	if step <= 0 {
+		return Segment{}, fmt.Errorf("idgen: segment step %d must be positive", step)
+	}
+	if next <= 0 {
+		next = 1
+	}
+	if next > math.MaxInt64-step {
+		return Segment{}, fmt.Errorf("idgen: segment %q exhausted at %d", tag, next)
+	}
+	return Segment{Start: next, End: next + step}, nil
+}
+
+// MemorySegmentStore 内存号段存储，仅用于单进程和测试，重启后从 1 开始
+type MemorySegmentStore struct {
+	mu   sync.Mutex
+	next map[string]int64
+}
+
+// NewMemorySegmentStore 创建内存号段存储
+func NewMemorySegmentStore() *MemorySegmentStore {
+	return &MemorySegmentStore{next: make(map[string]int64)}
+}
+
+func (s *MemorySegmentStore) Allocate(tag string, step int64) (Segment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segment, err := allocate(tag, s.next[tag], step)
+	if err != nil {
+		return Segment{}, err
+	}
+	s.next[tag] = segment.End
+	return segment, nil
+}
+
+// FileSegmentStore 文件号段存储，每个 tag 一个文件，内容为下一个未分配的 ID。
+// 使用目录锁在进程间互斥，同一主机上的多个进程可共享同一目录
+type FileSegmentStore struct {
+	dir string
+	mu  sync.Mutex // 文件锁只在进程间互斥，进程内额外加锁
+}
+
+// NewFileSegmentStore 创建文件号段存储，目录不存在时自动创建
+func NewFileSegmentStore(dir string) (*FileSegmentStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSegmentStore{dir: dir}, nil
+}
+
+func (s *FileSegmentStore) Allocate(tag string, step int64) (Segment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var segment Segment
+	err := fileutil.WithLock(filepath.Join(s.dir, ".lock"), func() error {
+		next, err := s.read(tag)
+		if err != nil {
+			return err
+		}
+		if segment, err = allocate(tag, next, step); err != nil {
+			return err
+		}
+		return s.write(tag, segment.End)
+	})
+	if err != nil {
+		return Segment{}, err
+	}
+	return segment, nil
+}
+
+func (s *FileSegmentStore) path(tag string) string {
+	return filepath.Join(s.dir, strings.NewReplacer("/", "_", "\\", "_").Replace(tag)+".segment")
+}
+
+// read 读取下一个未分配的 ID，文件不存在时返回 0。文件损坏时返回错误，避免重复发号
+func (s *FileSegmentStore) read(tag string) (int64, error) {
+	content, err := os.ReadFile(s.path(tag))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// write 原子地写入下一个未分配的 ID，崩溃后不会回退到已分配过的值
+func (s *FileSegmentStore) write(tag string, next int64) error {
+	return fileutil.WriteFileAtomic(s.path(tag), []byte(strconv.FormatInt(next, 10)))
+}
@@ -0,0 +1,229 @@
+package idgen
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// countingStore 记录每次取号的长度，可注入错误
+type countingStore struct {
+	SegmentStore
+	mu    sync.Mutex
+	calls []int64
+	err   error
+}
+
+func (s *countingStore) Allocate(tag string, step int64) (Segment, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, step)
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return Segment{}, err
+	}
+	return s.SegmentStore.Allocate(tag, step)
+}
+
+func (s *countingStore) steps() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.calls...)
+}
+
+func TestSegmentAllocator_Dense(t *testing.T) {
+	store := &countingStore{SegmentStore: NewMemorySegmentStore()}
+	allocator, err := NewSegmentAllocator(store, "orders", WithSegmentStep(10), WithSegmentStepRange(10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := int64(1); want <= 1000; want++ {
+		if id, err := allocator.NextId(); err != nil || id != want {
+			t.Fatalf("NextId() = %d, %v; want %d", id, err, want)
+		}
+	}
+	if calls := len(store.steps()); calls < 100 || calls > 101 {
+		t.Errorf("expected about 100 segment fetches, got %d", calls)
+	}
+}
+
+func TestSegmentAllocator_Concurrent(t *testing.T) {
+	store := NewMemorySegmentStore()
+	a1, _ := NewSegmentAllocator(store, "orders", WithSegmentStep(7))
+	a2, _ := NewSegmentAllocator(store, "orders", WithSegmentStep(7))
+
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(allocator *SegmentAllocator) {
+			defer wg.Done()
+			var last int64
+			for j := 0; j < 2000; j++ {
+				id, err := allocator.NextId()
+				if err != nil || id <= last {
+					t.Errorf("NextId() = %d, %v after %d", id, err, last)
+					return
+				}
+				last = id
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("duplicate id %d", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}([]*SegmentAllocator{a1, a2}[i%2])
+	}
+	wg.Wait()
+}
+
+func TestSegmentAllocator_Prefetch(t *testing.T) {
+	store := &countingStore{SegmentStore: NewMemorySegmentStore()}
+	allocator, _ := NewSegmentAllocator(store, "orders", WithSegmentStep(10), WithSegmentStepRange(10, 10))
+
+	for i := 0; i < 8; i++ {
+		allocator.NextId()
+	}
+	// 消耗 80% 后异步预取，等待预取完成后第 11 个 ID 无需再取号
+	deadline := time.Now().Add(time.Second)
+	for len(store.steps()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls := len(store.steps()); calls != 2 {
+		t.Fatalf("expected a prefetch after 80%% consumption, got %d fetches", calls)
+	}
+
+	// 预取失败时，耗尽号段的调用方同步重试并拿到错误
+	store.mu.Lock()
+	store.err = errors.New("store down")
+	store.mu.Unlock()
+	for i := 0; i < 10; i++ {
+		if _, err := allocator.NextId(); err != nil {
+			t.Fatalf("prefetched segment should still be served: %v", err)
+		}
+	}
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		_, err = allocator.NextId()
+	}
+	if err == nil || err.Error() != "store down" {
+		t.Errorf("expected store error, got %v", err)
+	}
+}
+
+func TestSegmentAllocator_AdaptiveStep(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Unix(1700000000, 0))
+	store := &countingStore{SegmentStore: NewMemorySegmentStore()}
+	allocator, _ := NewSegmentAllocator(store, "orders", WithSegmentStep(100), WithSegmentStepRange(50, 400),
+		WithSegmentTargetDuration(time.Minute), WithSegmentClock(clock))
+
+	consume := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := allocator.NextId(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	waitFetches := func(n int) {
+		deadline := time.Now().Add(time.Second)
+		for len(store.steps()) < n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// 号段瞬间耗尽，长度加倍直到上限
+	consume(100)
+	waitFetches(2)
+	consume(200)
+	waitFetches(3)
+	consume(400)
+	waitFetches(4)
+	if got := allocator.Step(); got != 400 {
+		t.Errorf("step after fast consumption = %d, want 400 (fetches %v)", got, store.steps())
+	}
+
+	// 号段消耗过慢，长度减半：第 5 个号段开始使用后 3 分钟才消耗到 80%
+	consume(400 + 81)
+	waitFetches(5)
+	clock.Advance(3 * time.Minute)
+	consume(319)
+	waitFetches(6)
+	if got := allocator.Step(); got != 200 {
+		t.Errorf("step after slow consumption = %d, want 200 (fetches %v)", got, store.steps())
+	}
+}
+
+func TestFileSegmentStore(t *testing.T) {
+	dir := t.TempDir()
+	s1, err := NewFileSegmentStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, _ := NewFileSegmentStore(dir)
+
+	var mu sync.Mutex
+	var segments []Segment
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(store SegmentStore) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				segment, err := store.Allocate("orders/2024", 5)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				segments = append(segments, segment)
+				mu.Unlock()
+			}
+		}([]SegmentStore{s1, s2}[i%2])
+	}
+	wg.Wait()
+
+	covered := make(map[int64]bool)
+	for _, segment := range segments {
+		for id := segment.Start; id < segment.End; id++ {
+			if covered[id] {
+				t.Fatalf("id %d allocated twice", id)
+			}
+			covered[id] = true
+		}
+	}
+
+	// 重新打开后从上次的位置继续
+	s3, _ := NewFileSegmentStore(dir)
+	if segment, err := s3.Allocate("orders/2024", 5); err != nil || segment.Start != 801 {
+		t.Errorf("Allocate after reopen = %+v, %v; want start 801", segment, err)
+	}
+	if segment, _ := s3.Allocate("invoices", 5); segment.Start != 1 {
+		t.Errorf("new tag should start at 1, got %+v", segment)
+	}
+	if _, err := s3.Allocate("invoices", 0); err == nil {
+		t.Errorf("expected error for non-positive step")
+	}
+}
+
+func TestNewSegmentAllocator_Invalid(t *testing.T) {
+	store := NewMemorySegmentStore()
+	invalid := [][]SegmentOption{
+		{WithSegmentStep(0)},
+		{WithSegmentStep(10), WithSegmentStepRange(20, 40)},
+		{WithSegmentPrefetchRatio(1.5)},
+		{WithSegmentTargetDuration(0)},
+	}
+	for _, opts := range invalid {
+		if _, err := NewSegmentAllocator(store, "orders", opts...); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+	if _, err := NewSegmentAllocator(nil, "orders"); err == nil {
+		t.Errorf("expected error for nil store")
+	}
+}
@@ -1,7 +1,226 @@
 package idgen
 
-import snowflake "github.com/dhlanshan/lotus/idgen/snowflake_inter"
+import (
+	"strconv"
+	"time"
+
+	snowflake "github.com/dhlanshan/lotus/idgen/snowflake_inter"
+)
+
+// Options 雪花算法配置，字段说明见 IdGeneratorOptions
+type Options = snowflake.IdGeneratorOptions
+
+// Generator 雪花 ID 生成器
+type Generator = snowflake.DefaultIdGenerator
+
+// OptionError 配置校验错误
+type OptionError = snowflake.OptionError
+
+// SnowflakeParts 雪花 ID 的组成部分
+type SnowflakeParts = snowflake.SnowflakeParts
+
+// OverCostActionArg 漂移/时间回拨事件参数
+type OverCostActionArg = snowflake.OverCostActionArg
+
+// GenIdActionHandler 漂移/时间回拨事件回调，通过 Options.GenIdActionHandler 设置
+type GenIdActionHandler = snowflake.GenIdActionHandler
+
+// 雪花计算方法，用于 Options.Method
+const (
+	MethodDrift       = snowflake.MethodDrift
+	MethodTraditional = snowflake.MethodTraditional
+	MethodLockFree    = snowflake.MethodLockFree
+)
+
+// ClockSkewPolicy 传统算法的时钟回拨策略，通过 Options.ClockSkewPolicy 设置
+type ClockSkewPolicy = snowflake.ClockSkewPolicy
+
+// 时钟回拨策略
+const (
+	ClockSkewTurnBack = snowflake.ClockSkewTurnBack
+	ClockSkewWait     = snowflake.ClockSkewWait
+	ClockSkewError    = snowflake.ClockSkewError
+)
+
+// 事件类型
+const (
+	ActionBeginOverCost = snowflake.ActionBeginOverCost
+	ActionEndOverCost   = snowflake.ActionEndOverCost
+	ActionBeginTurnBack = snowflake.ActionBeginTurnBack
+	ActionEndTurnBack   = snowflake.ActionEndTurnBack
+)
+
+// WorkerIdAssigner 机器码分配器，通过 Options.WorkerIdAssigner 设置
+type WorkerIdAssigner = snowflake.WorkerIdAssigner
+
+// WorkerIdLease 已分配的机器码
+type WorkerIdLease = snowflake.WorkerIdLease
+
+// StaticAssigner 固定机器码
+type StaticAssigner = snowflake.StaticAssigner
+
+// EnvAssigner 从环境变量或主机名序号读取机器码
+type EnvAssigner = snowflake.EnvAssigner
+
+// LeaseAssigner 基于租约注册表分配机器码
+type LeaseAssigner = snowflake.LeaseAssigner
+
+// LeaseStore 租约注册表使用的键值存储
+type LeaseStore = snowflake.LeaseStore
+
+// CheckpointStore 最后发号时间的检查点存储，通过 Options.CheckpointStore 设置
+type CheckpointStore = snowflake.CheckpointStore
+
+var (
+	// ErrInvalidOptions 配置不合法，可通过 errors.Is 判断
+	ErrInvalidOptions = snowflake.ErrInvalidOptions
+	// ErrInvalidId ID 与生成器配置不匹配
+	ErrInvalidId = snowflake.ErrInvalidId
+	// ErrClockMovedBackwards 时钟回拨，按 ClockSkewPolicy 无法继续发号
+	ErrClockMovedBackwards = snowflake.ErrClockMovedBackwards
+	// ErrWorkerIdUnavailable 无法分配机器码
+	ErrWorkerIdUnavailable = snowflake.ErrWorkerIdUnavailable
+	// ErrLeaseLost 机器码租约丢失，生成器停止发号
+	ErrLeaseLost = snowflake.ErrLeaseLost
+	// ErrGeneratorClosed 生成器已关闭
+	ErrGeneratorClosed = snowflake.ErrGeneratorClosed
+)
+
+// NewOptions 返回指定机器码的默认配置
+func NewOptions(workerId uint16) *Options {
+	return snowflake.NewIdGeneratorOptions(workerId)
+}
+
+// NewGenerator 根据配置创建独立的雪花 ID 生成器
+func NewGenerator(opts *Options) (*Generator, error) {
+	return snowflake.NewDefaultIdGenerator(opts)
+}
+
+// NewEnvAssigner 创建读取指定环境变量（为空时解析主机名序号）的分配器
+func NewEnvAssigner(envName string) *EnvAssigner {
+	return snowflake.NewEnvAssigner(envName)
+}
+
+// NewLeaseAssigner 创建租约分配器
+func NewLeaseAssigner(store LeaseStore, ttl time.Duration) *LeaseAssigner {
+	return snowflake.NewLeaseAssigner(store, ttl)
+}
+
+// NewMemoryLeaseStore 创建进程内租约存储，用于测试
+func NewMemoryLeaseStore() LeaseStore {
+	return snowflake.NewMemoryLeaseStore()
+}
+
+// NewFileLeaseStore 创建基于文件锁的租约存储
+func NewFileLeaseStore(dir string) (LeaseStore, error) {
+	store, err := snowflake.NewFileLeaseStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewFileCheckpointStore 创建文件检查点存储
+func NewFileCheckpointStore(dir string) (CheckpointStore, error) {
+	store, err := snowflake.NewFileCheckpointStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// SetSnowflakeOptions 重新配置 GenSnowflakeId 使用的默认生成器
+func SetSnowflakeOptions(opts *Options) error {
+	return snowflake.SetIdGenerator(opts)
+}
 
 func GenSnowflakeId() int64 {
 	return snowflake.NextId()
 }
+
+// DecomposeSnowflakeId 按默认生成器的配置拆解 GenSnowflakeId 生成的 ID
+func DecomposeSnowflakeId(id int64) (SnowflakeParts, error) {
+	return snowflake.Decompose(id)
+}
+
+// ParseSnowflakeId 按生成器的位布局解析十进制雪花 ID，generator 为 nil 时使用 GenSnowflakeId 的默认生成器。
+// 非十进制、负数以及从不会发出的序列数（0、小于 MinSeqNumber 的非回拨序列、大于 MaxSeqNumber）都会被拒绝，
+// 不合法时返回 *ParseError
+func ParseSnowflakeId(id string, generator *Generator) (SnowflakeParts, error) {
+	value, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return SnowflakeParts{}, malformed("snowflake", id, err)
+	}
+	var parts SnowflakeParts
+	if generator == nil {
+		parts, err = DecomposeSnowflakeId(value)
+	} else {
+		parts, err = generator.Decompose(value)
+	}
+	if err != nil {
+		return SnowflakeParts{}, malformed("snowflake", id, err)
+	}
+	return parts, nil
+}
+
+// snowflakeIdGenerator 雪花 ID 方案，ID 为十进制字符串
+type snowflakeIdGenerator struct {
+	generator *Generator
+}
+
+// NewSnowflakeIdGenerator 将雪花生成器包装为 IdGenerator，generator 为 nil 时使用 GenSnowflakeId 的默认生成器
+func NewSnowflakeIdGenerator(generator *Generator) IdGenerator {
+	return snowflakeIdGenerator{generator: generator}
+}
+
+func (g snowflakeIdGenerator) Scheme() string {
+	return "snowflake"
+}
+
+func (g snowflakeIdGenerator) Next() (string, error) {
+	if g.generator == nil {
+		return strconv.FormatInt(GenSnowflakeId(), 10), nil
+	}
+	id, err := g.generator.NextIdErr()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (g snowflakeIdGenerator) NextN(n int) ([]string, error) {
+	if g.generator == nil {
+		return nextN(n, g.Next)
+	}
+	ids := make([]int64, n)
+	if err := g.generator.FillErr(ids); err != nil {
+		return nil, err
+	}
+	result := make([]string, n)
+	for i, id := range ids {
+		result[i] = strconv.FormatInt(id, 10)
+	}
+	return result, nil
+}
+
+func (g snowflakeIdGenerator) decompose(id string) (SnowflakeParts, error) {
+	return ParseSnowflakeId(id, g.generator)
+}
+
+func (g snowflakeIdGenerator) Parse(id string) (ParsedId, error) {
+	parts, err := g.decompose(id)
+	if err != nil {
+		return ParsedId{}, err
+	}
+	return ParsedId{Scheme: g.Scheme(), Value: id, Timestamp: parts.Time}, nil
+}
+
+func (g snowflakeIdGenerator) Validate(id string) error {
+	_, err := g.decompose(id)
+	return err
+}
+
+func (g snowflakeIdGenerator) Timestamp(id string) (time.Time, error) {
+	parts, err := g.decompose(id)
+	return parts.Time, err
+}
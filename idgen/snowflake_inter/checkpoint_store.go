@@ -0,0 +1,64 @@
+package snowflake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhlanshan/lotus/internal/fileutil"
+)
+
+// CheckpointStore 持久化每个机器码已发出 ID 的时间上界，重启后据此避免时钟回拨导致重复发号
+type CheckpointStore interface {
+	// Load 读取检查点，不存在时 ok 为 false
+	Load(key string) (lastTime time.Time, ok bool, err error)
+	// Save 保存检查点，运行期间保存的检查点已包含未持久化发号的余量，正常关闭时保存最后发号的精确时间
+	Save(key string, lastTime time.Time) error
+}
+
+// FileCheckpointStore 文件检查点存储，每个 key 一个文件，内容为毫秒时间戳
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCheckpointStore 创建检查点存储，目录不存在时自动创建
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+func (s *FileCheckpointStore) Load(key string) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	millis, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(millis), true, nil
+}
+
+// Save 原子地写入检查点，避免崩溃时留下写了一半的检查点
+func (s *FileCheckpointStore) Save(key string, lastTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fileutil.WriteFileAtomic(s.path(key), []byte(strconv.FormatInt(lastTime.UnixMilli(), 10)))
+}
+
+func (s *FileCheckpointStore) path(key string) string {
+	return filepath.Join(s.dir, strings.NewReplacer("/", "_", "\\", "_").Replace(key)+".checkpoint")
+}
@@ -0,0 +1,149 @@
+package snowflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+func newCheckpointOptions(store CheckpointStore, clock *manualClock) *IdGeneratorOptions {
+	options := NewIdGeneratorOptions(4)
+	options.Clock = clock
+	options.CheckpointStore = store
+	options.CheckpointInterval = 100 * time.Millisecond
+	options.TopOverCostCount = 50
+	return options
+}
+
+// 测试文件检查点存储
+func TestFileCheckpointStore(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok, err := store.Load("worker"); ok || err != nil {
+		t.Fatalf("expected missing checkpoint, got %v, %v", ok, err)
+	}
+
+	now := time.UnixMilli(time.Now().UnixMilli())
+	if err = store.Save("worker", now); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if lastTime, ok, err := store.Load("worker"); !ok || err != nil || !lastTime.Equal(now) {
+		t.Errorf("Load() = %v, %v, %v; want %v", lastTime, ok, err, now)
+	}
+}
+
+// 测试重启时时钟回拨不会发出早于检查点的 ID
+func TestGeneratorCheckpoint(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	clock := newManualClock()
+
+	generator, err := NewDefaultIdGenerator(newCheckpointOptions(store, clock))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	last := generator.NextIds(1000)[999]
+	if err = generator.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok, _ := store.Load("snowflake-0-4"); !ok {
+		t.Fatal("expected checkpoint to be saved on Close")
+	}
+
+	// 重启前时钟回拨 1 秒，默认拒绝启动
+	clock.Add(-time.Second)
+	if _, err = NewDefaultIdGenerator(newCheckpointOptions(store, clock)); !errors.Is(err, ErrClockMovedBackwards) {
+		t.Fatalf("expected ErrClockMovedBackwards, got %v", err)
+	}
+
+	// 允许等待时阻塞到时钟越过检查点
+	options := newCheckpointOptions(store, clock)
+	options.CheckpointMaxWait = 2 * time.Second
+	generator, err = NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer generator.Close()
+	if id := generator.NextId(); id <= last {
+		t.Errorf("id %d after restart is not greater than %d issued before", id, last)
+	}
+}
+
+// 测试正常关闭后立即重启无需等待检查点余量
+func TestGeneratorCheckpoint_Restart(t *testing.T) {
+	for _, method := range []uint16{MethodDrift, MethodTraditional, MethodLockFree} {
+		store, err := NewFileCheckpointStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		clock := newManualClock()
+
+		var last int64
+		for restart := 0; restart < 3; restart++ {
+			options := newCheckpointOptions(store, clock)
+			options.Method = method
+			generator, err := NewDefaultIdGenerator(options)
+			if err != nil {
+				t.Fatalf("method %d restart %d: unexpected error %v", method, restart, err)
+			}
+			if id := generator.NextId(); id <= last {
+				t.Errorf("method %d: id %d after restart is not greater than %d", method, id, last)
+			}
+			last = generator.NextIds(100)[99]
+			if err = generator.Close(); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			lastTime, _, _ := store.Load("snowflake-0-4")
+			if want := generator.ExtractTime(last); !lastTime.Equal(want) {
+				t.Errorf("method %d: checkpoint %v, want last issued time %v", method, lastTime, want)
+			}
+			clock.Add(time.Millisecond)
+		}
+	}
+}
+
+// 测试空闲后发号再崩溃，回拨后的重启不会发出更小的 ID
+func TestGeneratorCheckpoint_Crash(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t0 := time.Now()
+	clock := timeutil.NewFakeClock(t0)
+	options := NewIdGeneratorOptions(4)
+	options.Method = MethodTraditional
+	options.Clock = clock
+	options.CheckpointStore = store
+	options.CheckpointInterval = time.Second
+
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer generator.Close()
+	generator.NextId()
+	clock.Advance(10 * time.Second)
+	clock.Advance(time.Second)
+	last := generator.NextId()
+
+	// 不调用 Close 模拟崩溃，重启时时钟回到 t0+5s
+	restart := *options
+	restart.Clock = timeutil.NewFakeClock(t0.Add(5 * time.Second))
+	restarted, err := NewDefaultIdGenerator(&restart)
+	if errors.Is(err, ErrClockMovedBackwards) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer restarted.Close()
+	if id := restarted.NextId(); id <= last {
+		t.Errorf("id %d after crash restart is not greater than %d issued before", id, last)
+	}
+}
@@ -1,33 +1,54 @@
 package snowflake
 
 import (
+	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
 )
 
-type defaultIdGenerator struct {
-	Options              *idGeneratorOptions
-	SnowWorker           iSnowWorker
-	IdGeneratorException idGeneratorException
+// DefaultIdGenerator 雪花 ID 生成器，由 NewDefaultIdGenerator 创建
+type DefaultIdGenerator struct {
+	Options    *IdGeneratorOptions
+	SnowWorker iSnowWorker
+
+	clock        timeutil.Clock
+	lease        WorkerIdLease
+	issueMu      sync.RWMutex // 发号持有读锁，Close 持有写锁等待进行中的发号结束
+	checkpointMu sync.Mutex
+	checkpoint   atomic.Int64 // 已持久化的检查点时间戳，与 LastTimeTick 同一基准
+	saveWg       sync.WaitGroup
+	stopErr      atomic.Pointer[error] // 非空时停止发号
+	closeOnce    sync.Once
+	closed       chan struct{}
 }
 
-func newDefaultIdGenerator(options *idGeneratorOptions) *defaultIdGenerator {
+// NewDefaultIdGenerator 根据配置创建生成器，配置不合法时返回 *OptionError
+func NewDefaultIdGenerator(options *IdGeneratorOptions) (*DefaultIdGenerator, error) {
 	if options == nil {
-		panic("dig.Options error.")
+		return nil, newOptionError("Options", "options is nil")
+	}
+
+	var clock = options.Clock
+	if clock == nil {
+		clock = timeutil.RealClock{}
 	}
 
 	// 1.BaseTime
 	minTime := int64(631123200000) // time.Now().AddDate(-30, 0, 0).UnixNano() / 1e6
-	if options.BaseTime < minTime || options.BaseTime > time.Now().UnixNano()/1e6 {
-		panic("BaseTime error.")
+	if options.BaseTime < minTime || options.BaseTime > clock.Now().UnixNano()/1e6 {
+		return nil, newOptionError("BaseTime", "BaseTime error.")
 	}
 
 	// 2.WorkerIdBitLength
 	if options.WorkerIdBitLength <= 0 {
-		panic("WorkerIdBitLength error.(range:[1, 21])")
+		return nil, newOptionError("WorkerIdBitLength", "WorkerIdBitLength error.(range:[1, 21])")
 	}
-	if options.WorkerIdBitLength+options.SeqBitLength > 22 {
-		panic("error：WorkerIdBitLength + SeqBitLength <= 22")
+	if options.timestampShift() > 22 {
+		return nil, newOptionError("WorkerIdBitLength", "error：FlagBit + DataCenterIdBitLength + WorkerIdBitLength + SeqBitLength <= 22")
 	}
 
 	// 3.WorkerId
@@ -35,13 +56,19 @@ func newDefaultIdGenerator(options *idGeneratorOptions) *defaultIdGenerator {
 	if maxWorkerIdNumber == 0 {
 		maxWorkerIdNumber = 63
 	}
-	if options.WorkerId < 0 || options.WorkerId > maxWorkerIdNumber {
-		panic("WorkerId error. (range:[0, " + strconv.FormatUint(uint64(maxWorkerIdNumber), 10) + "]")
+	if options.WorkerIdAssigner == nil && options.WorkerId > maxWorkerIdNumber {
+		return nil, newOptionError("WorkerId", "WorkerId error. (range:[0, "+strconv.FormatUint(uint64(maxWorkerIdNumber), 10)+"]")
+	}
+
+	// 3.1.DataCenterId
+	maxDataCenterId := uint16(1<<options.DataCenterIdBitLength) - 1
+	if options.DataCenterId > maxDataCenterId {
+		return nil, newOptionError("DataCenterId", "DataCenterId error. (range:[0, "+strconv.FormatUint(uint64(maxDataCenterId), 10)+"]")
 	}
 
 	// 4.SeqBitLength
 	if options.SeqBitLength < 2 || options.SeqBitLength > 21 {
-		panic("SeqBitLength error. (range:[2, 21])")
+		return nil, newOptionError("SeqBitLength", "SeqBitLength error. (range:[2, 21])")
 	}
 
 	// 5.MaxSeqNumber
@@ -50,17 +77,38 @@ func newDefaultIdGenerator(options *idGeneratorOptions) *defaultIdGenerator {
 		maxSeqNumber = 63
 	}
 	if options.MaxSeqNumber < 0 || options.MaxSeqNumber > maxSeqNumber {
-		panic("MaxSeqNumber error. (range:[1, " + strconv.FormatUint(uint64(maxSeqNumber), 10) + "]")
+		return nil, newOptionError("MaxSeqNumber", "MaxSeqNumber error. (range:[1, "+strconv.FormatUint(uint64(maxSeqNumber), 10)+"]")
 	}
 
 	// 6.MinSeqNumber
 	if options.MinSeqNumber < 5 || options.MinSeqNumber > maxSeqNumber {
-		panic("MinSeqNumber error. (range:[5, " + strconv.FormatUint(uint64(maxSeqNumber), 10) + "]")
+		return nil, newOptionError("MinSeqNumber", "MinSeqNumber error. (range:[5, "+strconv.FormatUint(uint64(maxSeqNumber), 10)+"]")
 	}
 
 	// 7.TopOverCostCount
 	if options.TopOverCostCount < 0 || options.TopOverCostCount > 10000 {
-		panic("TopOverCostCount error. (range:[0, 10000]")
+		return nil, newOptionError("TopOverCostCount", "TopOverCostCount error. (range:[0, 10000]")
+	}
+
+	// 7.1.Method
+	if options.Method < MethodDrift || options.Method > MethodLockFree {
+		return nil, newOptionError("Method", "Method error. (range:[1, 3])")
+	}
+
+	// 8.WorkerIdAssigner
+	var lease WorkerIdLease
+	if options.WorkerIdAssigner != nil {
+		var err error
+		if lease, err = options.WorkerIdAssigner.Assign(maxWorkerIdNumber); err != nil {
+			return nil, err
+		}
+		if lease.WorkerId() > maxWorkerIdNumber {
+			_ = lease.Release()
+			return nil, newOptionError("WorkerId", "WorkerId error. (range:[0, "+strconv.FormatUint(uint64(maxWorkerIdNumber), 10)+"]")
+		}
+		assigned := *options
+		assigned.WorkerId = lease.WorkerId()
+		options = &assigned
 	}
 
 	var snowWorker iSnowWorker
@@ -69,24 +117,271 @@ func newDefaultIdGenerator(options *idGeneratorOptions) *defaultIdGenerator {
 		snowWorker = newSnowWorkerM1(options)
 	case 2:
 		snowWorker = newSnowWorkerM2(options)
-	default:
-		snowWorker = newSnowWorkerM1(options)
+	case 3:
+		snowWorker = newSnowWorkerM3(options)
 	}
 
 	if options.Method == 1 {
-		time.Sleep(time.Duration(500) * time.Microsecond)
+		clock.Sleep(time.Duration(500) * time.Microsecond)
 	}
 
-	return &defaultIdGenerator{
+	dig := &DefaultIdGenerator{
 		Options:    options,
 		SnowWorker: snowWorker,
+		clock:      clock,
+		lease:      lease,
+		closed:     make(chan struct{}),
+	}
+
+	// 9.CheckpointStore
+	if options.CheckpointStore != nil {
+		if err := dig.waitCheckpoint(); err != nil {
+			if lease != nil {
+				_ = lease.Release()
+			}
+			return nil, err
+		}
+		dig.saveWg.Add(1)
+		go dig.saveCheckpoints()
+	}
+
+	if lease != nil && lease.Lost() != nil {
+		go dig.watchLease()
+	}
+	return dig, nil
+}
+
+// checkpointKey 检查点按数据中心和机器码区分
+func (dig *DefaultIdGenerator) checkpointKey() string {
+	return fmt.Sprintf("snowflake-%d-%d", dig.Options.DataCenterId, dig.Options.WorkerId)
+}
+
+func (dig *DefaultIdGenerator) checkpointInterval() time.Duration {
+	if dig.Options.CheckpointInterval > 0 {
+		return dig.Options.CheckpointInterval
+	}
+	return time.Second
+}
+
+// checkpointMargin 运行期间保存的检查点需要加上的余量：下一次保存前最多还会发出一个保存间隔的 ID，
+// 漂移算法还可能借用最多 TopOverCostCount 毫秒的未来时间
+func (dig *DefaultIdGenerator) checkpointMargin() time.Duration {
+	margin := dig.checkpointInterval()
+	if dig.Options.Method != MethodTraditional {
+		margin += time.Duration(dig.Options.TopOverCostCount) * time.Millisecond
+	}
+	return margin
+}
+
+// waitCheckpoint 等待当前时间越过检查点所在的毫秒。余量在保存时已计入检查点，
+// 正常关闭保存的是最后发号的精确时间，重启时无需等待；
+// 漂移算法关闭前可能借用了最多 TopOverCostCount 毫秒的未来时间，这段时间总是允许等待
+func (dig *DefaultIdGenerator) waitCheckpoint() error {
+	lastTime, ok, err := dig.Options.CheckpointStore.Load(dig.checkpointKey())
+	if err != nil || !ok {
+		return err
+	}
+
+	safeTime := lastTime.Add(time.Millisecond)
+	maxWait := dig.Options.CheckpointMaxWait
+	if dig.Options.Method != MethodTraditional {
+		maxWait += time.Duration(dig.Options.TopOverCostCount) * time.Millisecond
+	}
+	deadline := dig.clock.Now().Add(maxWait)
+	for now := dig.clock.Now(); now.Before(safeTime); now = dig.clock.Now() {
+		wait := safeTime.Sub(now)
+		if now.Add(wait).After(deadline) {
+			return fmt.Errorf("%w: checkpoint %v is %d milliseconds ahead of clock", ErrClockMovedBackwards, lastTime, safeTime.Sub(now).Milliseconds())
+		}
+		dig.clock.Sleep(wait)
+	}
+	return nil
+}
+
+// lastIssued 最后发号的时间，尚未发号时为零值
+func (dig *DefaultIdGenerator) lastIssued() time.Time {
+	lastTimeTick := dig.SnowWorker.LastTimeTick()
+	if lastTimeTick <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(lastTimeTick + dig.Options.BaseTime)
+}
+
+// waitPast 等待时钟越过 lastTime 所在的毫秒
+func (dig *DefaultIdGenerator) waitPast(lastTime time.Time) {
+	if lastTime.IsZero() {
+		return
+	}
+	safeTime := lastTime.Add(time.Millisecond)
+	for now := dig.clock.Now(); now.Before(safeTime); now = dig.clock.Now() {
+		dig.clock.Sleep(safeTime.Sub(now))
+	}
+}
+
+// saveCheckpoints 定期续期检查点，空闲时也随时间推进，保证发号前检查点已覆盖当前时间；
+// 生成器关闭后不再发号，最后一次保存最后发号的精确时间
+func (dig *DefaultIdGenerator) saveCheckpoints() {
+	defer dig.saveWg.Done()
+
+	ticker := dig.clock.NewTicker(dig.checkpointInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.Chan():
+			_ = dig.renewCheckpoint(0)
+		case <-dig.closed:
+			dig.saveFinalCheckpoint()
+			return
+		}
+	}
+}
+
+// renewCheckpoint 保存 max(最后发号时间, 当前时间, minTick) 加上 checkpointMargin 的检查点，
+// 尚未发号时无需保存
+func (dig *DefaultIdGenerator) renewCheckpoint(minTick int64) error {
+	dig.checkpointMu.Lock()
+	defer dig.checkpointMu.Unlock()
+
+	lastTimeTick := dig.SnowWorker.LastTimeTick()
+	if lastTimeTick <= 0 && minTick <= 0 {
+		return nil
+	}
+	if minTick > 0 && minTick <= dig.checkpoint.Load() {
+		return nil
+	}
+	tick := max(lastTimeTick, minTick, dig.clock.Now().UnixMilli()-dig.Options.BaseTime) + dig.checkpointMargin().Milliseconds()
+	if tick <= dig.checkpoint.Load() {
+		return nil
+	}
+	if err := dig.Options.CheckpointStore.Save(dig.checkpointKey(), time.UnixMilli(tick+dig.Options.BaseTime)); err != nil {
+		return err
+	}
+	dig.checkpoint.Store(tick)
+	return nil
+}
+
+// saveFinalCheckpoint 关闭时保存最后发号的精确时间，重启时无需等待余量
+func (dig *DefaultIdGenerator) saveFinalCheckpoint() {
+	dig.checkpointMu.Lock()
+	defer dig.checkpointMu.Unlock()
+
+	lastTimeTick := dig.SnowWorker.LastTimeTick()
+	if lastTimeTick <= 0 {
+		return
+	}
+	if err := dig.Options.CheckpointStore.Save(dig.checkpointKey(), time.UnixMilli(lastTimeTick+dig.Options.BaseTime)); err == nil {
+		dig.checkpoint.Store(lastTimeTick)
+	}
+}
+
+// coverCheckpoint 确保已持久化的检查点不早于 ids 中最大的 ID，未覆盖时同步续期，
+// 续期失败时这些 ID 不能交给调用方，否则崩溃重启后可能重复
+func (dig *DefaultIdGenerator) coverCheckpoint(ids ...int64) error {
+	if dig.Options.CheckpointStore == nil {
+		return nil
+	}
+	var tick int64
+	for _, id := range ids {
+		tick = max(tick, id>>dig.Options.timestampShift())
+	}
+	if tick <= dig.checkpoint.Load() {
+		return nil
+	}
+	return dig.renewCheckpoint(tick)
+}
+
+// watchLease 租约丢失后停止发号
+func (dig *DefaultIdGenerator) watchLease() {
+	select {
+	case <-dig.lease.Lost():
+		dig.stop(ErrLeaseLost)
+	case <-dig.closed:
+	}
+}
+
+func (dig *DefaultIdGenerator) stop(err error) {
+	dig.stopErr.CompareAndSwap(nil, &err)
+}
+
+// NextIdErr 生成下一个 ID，租约丢失、生成器关闭、按 ClockSkewPolicy 拒绝时钟回拨或检查点保存失败时返回错误
+func (dig *DefaultIdGenerator) NextIdErr() (int64, error) {
+	dig.issueMu.RLock()
+	defer dig.issueMu.RUnlock()
+
+	if err := dig.stopErr.Load(); err != nil {
+		return 0, *err
+	}
+	id, err := dig.SnowWorker.NextId()
+	if err != nil {
+		return 0, err
+	}
+	if err = dig.coverCheckpoint(id); err != nil {
+		return 0, err
 	}
+	return id, nil
+}
+
+// NextId 生成下一个 ID，无法发号时 panic
+func (dig *DefaultIdGenerator) NextId() int64 {
+	id, err := dig.NextIdErr()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// NextIds 一次加锁生成 n 个 ID，唯一性和递增性与连续调用 NextId 相同
+func (dig *DefaultIdGenerator) NextIds(n int) []int64 {
+	ids := make([]int64, n)
+	dig.Fill(ids)
+	return ids
+}
+
+// Fill 一次加锁生成 ID 填满 ids，无法发号时 panic
+func (dig *DefaultIdGenerator) Fill(ids []int64) {
+	if err := dig.FillErr(ids); err != nil {
+		panic(err)
+	}
+}
+
+// FillErr 同 Fill，无法发号时返回错误，除检查点保存失败外，出错前已填充的 ID 仍然有效
+func (dig *DefaultIdGenerator) FillErr(ids []int64) error {
+	dig.issueMu.RLock()
+	defer dig.issueMu.RUnlock()
+
+	if err := dig.stopErr.Load(); err != nil {
+		return *err
+	}
+	err := dig.SnowWorker.NextIds(ids)
+	if coverErr := dig.coverCheckpoint(ids...); coverErr != nil {
+		clear(ids)
+		return coverErr
+	}
+	return err
+}
+
+// Close 停止发号，保存检查点并释放机器码租约
+func (dig *DefaultIdGenerator) Close() error {
+	var err error
+	dig.closeOnce.Do(func() {
+		dig.stop(ErrGeneratorClosed)
+		// 等待进行中的发号结束，最终检查点才能覆盖所有已发出的 ID
+		dig.issueMu.Lock()
+		dig.issueMu.Unlock()
+		close(dig.closed)
+		dig.saveWg.Wait()
+		if dig.lease != nil {
+			err = dig.lease.Release()
+		}
+	})
+	return err
 }
 
-func (dig defaultIdGenerator) NewLong() int64 {
-	return dig.SnowWorker.NextId()
+func (dig *DefaultIdGenerator) NewLong() int64 {
+	return dig.NextId()
 }
 
-func (dig defaultIdGenerator) ExtractTime(id int64) time.Time {
-	return time.UnixMilli(id>>(dig.Options.WorkerIdBitLength+dig.Options.SeqBitLength) + dig.Options.BaseTime)
+func (dig *DefaultIdGenerator) ExtractTime(id int64) time.Time {
+	return time.UnixMilli(id>>dig.Options.timestampShift() + dig.Options.BaseTime)
 }
@@ -0,0 +1,308 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 测试合法配置创建生成器
+func TestNewDefaultIdGenerator(t *testing.T) {
+	for _, method := range []uint16{1, 2} {
+		options := NewIdGeneratorOptions(3)
+		options.Method = method
+		generator, err := NewDefaultIdGenerator(options)
+		if err != nil {
+			t.Fatalf("method %d: unexpected error %v", method, err)
+		}
+
+		seen := make(map[int64]struct{})
+		var last int64
+		for i := 0; i < 10000; i++ {
+			id := generator.NextId()
+			if _, ok := seen[id]; ok {
+				t.Fatalf("method %d: duplicate id %d", method, id)
+			}
+			if id <= last {
+				t.Fatalf("method %d: id %d not greater than %d", method, id, last)
+			}
+			seen[id] = struct{}{}
+			last = id
+		}
+	}
+}
+
+// 测试非法配置返回类型化错误
+func TestNewDefaultIdGenerator_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *IdGeneratorOptions)
+		field  string
+	}{
+		{"base time too small", func(o *IdGeneratorOptions) { o.BaseTime = 1 }, "BaseTime"},
+		{"zero worker bits", func(o *IdGeneratorOptions) { o.WorkerIdBitLength = 0 }, "WorkerIdBitLength"},
+		{"bit budget", func(o *IdGeneratorOptions) { o.WorkerIdBitLength = 12; o.SeqBitLength = 12 }, "WorkerIdBitLength"},
+		{"worker id too large", func(o *IdGeneratorOptions) { o.WorkerId = 64 }, "WorkerId"},
+		{"seq bits too small", func(o *IdGeneratorOptions) { o.SeqBitLength = 1 }, "SeqBitLength"},
+		{"max seq too large", func(o *IdGeneratorOptions) { o.MaxSeqNumber = 64 }, "MaxSeqNumber"},
+		{"min seq reserved", func(o *IdGeneratorOptions) { o.MinSeqNumber = 4 }, "MinSeqNumber"},
+		{"top over cost", func(o *IdGeneratorOptions) { o.TopOverCostCount = 10001 }, "TopOverCostCount"},
+		{"unknown method", func(o *IdGeneratorOptions) { o.Method = 4 }, "Method"},
+		{"zero method", func(o *IdGeneratorOptions) { o.Method = 0 }, "Method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := NewIdGeneratorOptions(1)
+			tt.modify(options)
+			_, err := NewDefaultIdGenerator(options)
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Fatalf("expected ErrInvalidOptions, got %v", err)
+			}
+			var optionErr *OptionError
+			if !errors.As(err, &optionErr) || optionErr.Field != tt.field {
+				t.Errorf("expected field %s, got %v", tt.field, err)
+			}
+		})
+	}
+
+	if _, err := NewDefaultIdGenerator(nil); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions for nil options, got %v", err)
+	}
+}
+
+// 测试重新配置默认生成器
+func TestSetIdGenerator(t *testing.T) {
+	defer SetIdGenerator(NewIdGeneratorOptions(1))
+
+	if err := SetIdGenerator(NewIdGeneratorOptions(7)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	id := NextId()
+	if workerId := (id >> 6) & 63; workerId != 7 {
+		t.Errorf("expected worker id 7, got %d", workerId)
+	}
+
+	invalid := NewIdGeneratorOptions(7)
+	invalid.SeqBitLength = 1
+	if err := SetIdGenerator(invalid); err == nil {
+		t.Fatal("expected error for invalid options")
+	}
+	if workerId := (NextId() >> 6) & 63; workerId != 7 {
+		t.Errorf("invalid options should keep previous generator, got worker id %d", workerId)
+	}
+}
+
+// 测试漂移借用未来时间后以相同机器码替换生成器，新生成器的 ID 仍然更大
+func TestSetIdGenerator_SameWorker(t *testing.T) {
+	defer SetIdGenerator(NewIdGeneratorOptions(1))
+
+	options := NewIdGeneratorOptions(7)
+	options.Clock = newManualClock()
+	if err := SetIdGenerator(options); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var last int64
+	for i := 0; i < 1000; i++ {
+		last = NextId()
+	}
+	if err := SetIdGenerator(options); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if id := NextId(); id <= last {
+		t.Errorf("id %d after SetIdGenerator is not greater than %d issued before", id, last)
+	}
+}
+
+// 测试 ID 拆解与组装
+func TestDecomposeCompose(t *testing.T) {
+	options := NewIdGeneratorOptions(700)
+	options.WorkerIdBitLength = 10
+	options.SeqBitLength = 12
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	before := time.Now().Add(-time.Millisecond)
+	id := generator.NextId()
+	parts, err := generator.Decompose(id)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if parts.WorkerId != 700 {
+		t.Errorf("expected worker id 700, got %d", parts.WorkerId)
+	}
+	if parts.SeqNumber != options.MinSeqNumber {
+		t.Errorf("expected sequence %d, got %d", options.MinSeqNumber, parts.SeqNumber)
+	}
+	if parts.Time.Before(before) || parts.Time.After(time.Now().Add(time.Millisecond)) {
+		t.Errorf("unexpected time %v", parts.Time)
+	}
+	if !parts.Time.Equal(generator.ExtractTime(id)) {
+		t.Errorf("Decompose time %v differs from ExtractTime %v", parts.Time, generator.ExtractTime(id))
+	}
+
+	composed, err := generator.Compose(parts)
+	if err != nil || composed != id {
+		t.Errorf("Compose(%+v) = %d, %v; want %d", parts, composed, err, id)
+	}
+
+	turnBack, _ := generator.Compose(SnowflakeParts{TimeTick: 100, WorkerId: 1, SeqNumber: 3})
+	if parts, _ := generator.Decompose(turnBack); !parts.IsTurnBack || parts.TimeTick != 100 {
+		t.Errorf("expected turn back id at tick 100, got %+v", parts)
+	}
+
+	future, _ := generator.Compose(SnowflakeParts{Time: time.Now().Add(time.Hour), SeqNumber: 5})
+	if parts, _ := generator.Decompose(future); !parts.IsOverCost {
+		t.Errorf("expected over cost id, got %+v", parts)
+	}
+
+	if _, err := generator.Compose(SnowflakeParts{WorkerId: 1024}); !errors.Is(err, ErrInvalidId) {
+		t.Errorf("expected ErrInvalidId, got %v", err)
+	}
+	if _, err := generator.Decompose(-1); !errors.Is(err, ErrInvalidId) {
+		t.Errorf("expected ErrInvalidId, got %v", err)
+	}
+	reserved, _ := generator.Compose(SnowflakeParts{TimeTick: 100, WorkerId: 1, SeqNumber: 0})
+	if _, err := generator.Decompose(reserved); !errors.Is(err, ErrInvalidId) {
+		t.Errorf("expected ErrInvalidId for reserved sequence, got %v", err)
+	}
+}
+
+// 测试批量生成与连续调用 NextId 的唯一性和递增性一致
+func TestNextIds(t *testing.T) {
+	for _, method := range []uint16{1, 2} {
+		options := NewIdGeneratorOptions(3)
+		options.Method = method
+		generator, err := NewDefaultIdGenerator(options)
+		if err != nil {
+			t.Fatalf("method %d: unexpected error %v", method, err)
+		}
+
+		ids := generator.NextIds(5000)
+		ids = append(ids, generator.NextId())
+		batch := make([]int64, 3000)
+		generator.Fill(batch)
+		ids = append(ids, batch...)
+
+		for i := 1; i < len(ids); i++ {
+			if ids[i] <= ids[i-1] {
+				t.Fatalf("method %d: ids[%d]=%d not greater than ids[%d]=%d", method, i, ids[i], i-1, ids[i-1])
+			}
+		}
+		for _, id := range ids {
+			parts, err := generator.Decompose(id)
+			if err != nil || parts.WorkerId != 3 || parts.SeqNumber < options.MinSeqNumber {
+				t.Fatalf("method %d: unexpected id %d: %+v, %v", method, id, parts, err)
+			}
+		}
+	}
+}
+
+func newBenchmarkGenerator(b *testing.B) *DefaultIdGenerator {
+	options := NewIdGeneratorOptions(1)
+	options.WorkerIdBitLength = 6
+	options.SeqBitLength = 16
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		b.Fatalf("unexpected error %v", err)
+	}
+	return generator
+}
+
+func BenchmarkNextId(b *testing.B) {
+	generator := newBenchmarkGenerator(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = generator.NextId()
+	}
+}
+
+func BenchmarkNextIds(b *testing.B) {
+	for _, size := range []int{16, 256, 4096} {
+		b.Run("batch "+strconv.Itoa(size), func(b *testing.B) {
+			generator := newBenchmarkGenerator(b)
+			ids := make([]int64, size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i += size {
+				generator.Fill(ids)
+			}
+		})
+	}
+}
+
+func BenchmarkNextId_Parallel(b *testing.B) {
+	generator := newBenchmarkGenerator(b)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = generator.NextId()
+		}
+	})
+}
+
+func BenchmarkNextIds_Parallel(b *testing.B) {
+	generator := newBenchmarkGenerator(b)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ids := make([]int64, 256)
+		for n := 0; pb.Next(); n++ {
+			if n%len(ids) == 0 {
+				generator.Fill(ids)
+			}
+		}
+	})
+}
+
+// 测试多数据中心布局
+func TestDataCenterLayout(t *testing.T) {
+	for _, method := range []uint16{MethodDrift, MethodTraditional, MethodLockFree} {
+		options := NewIdGeneratorOptions(21)
+		options.Method = method
+		options.DataCenterIdBitLength = 5
+		options.DataCenterId = 17
+		options.WorkerIdBitLength = 5
+		options.SeqBitLength = 11
+		options.ReserveFlagBit = true
+		options.Flag = true
+		generator, err := NewDefaultIdGenerator(options)
+		if err != nil {
+			t.Fatalf("method %d: unexpected error %v", method, err)
+		}
+
+		ids := generator.NextIds(100)
+		ids = append(ids, generator.NextId())
+		for _, id := range ids {
+			parts, err := generator.Decompose(id)
+			if err != nil {
+				t.Fatalf("method %d: unexpected error %v", method, err)
+			}
+			if !parts.Flag || parts.DataCenterId != 17 || parts.WorkerId != 21 {
+				t.Fatalf("method %d: unexpected parts %+v", method, parts)
+			}
+			if time.Since(parts.Time) > time.Second {
+				t.Fatalf("method %d: unexpected time %v", method, parts.Time)
+			}
+			if composed, err := generator.Compose(parts); err != nil || composed != id {
+				t.Fatalf("method %d: Compose(%+v) = %d, %v; want %d", method, parts, composed, err, id)
+			}
+		}
+	}
+
+	options := NewIdGeneratorOptions(1)
+	options.DataCenterIdBitLength = 10
+	options.ReserveFlagBit = true
+	if _, err := NewDefaultIdGenerator(options); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected bit budget error, got %v", err)
+	}
+
+	options = NewIdGeneratorOptions(1)
+	options.DataCenterIdBitLength = 3
+	options.DataCenterId = 8
+	var optionErr *OptionError
+	if _, err := NewDefaultIdGenerator(options); !errors.As(err, &optionErr) || optionErr.Field != "DataCenterId" {
+		t.Errorf("expected DataCenterId error, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package snowflake
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LeaseAssigner 基于租约注册表的机器码分配器，后台定期续约，续约失败时租约丢失
+type LeaseAssigner struct {
+	Store         LeaseStore
+	KeyPrefix     string        // 注册表 key 前缀，默认 "snowflake/worker/"
+	Owner         string        // 租约持有者标识，默认 主机名-进程号-启动时间
+	TTL           time.Duration // 租约有效期，默认 30s
+	RenewInterval time.Duration // 续约间隔，默认 TTL/3
+}
+
+// NewLeaseAssigner 创建租约分配器
+func NewLeaseAssigner(store LeaseStore, ttl time.Duration) *LeaseAssigner {
+	return &LeaseAssigner{Store: store, TTL: ttl}
+}
+
+func (a *LeaseAssigner) Assign(maxWorkerId uint16) (WorkerIdLease, error) {
+	if a.Store == nil {
+		return nil, fmt.Errorf("%w: lease store is nil", ErrWorkerIdUnavailable)
+	}
+
+	lease := &workerIdLease{
+		store:    a.Store,
+		owner:    a.Owner,
+		ttl:      a.TTL,
+		interval: a.RenewInterval,
+		lost:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	if lease.owner == "" {
+		hostname, _ := os.Hostname()
+		lease.owner = hostname + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	if lease.ttl <= 0 {
+		lease.ttl = 30 * time.Second
+	}
+	if lease.interval <= 0 || lease.interval >= lease.ttl {
+		lease.interval = lease.ttl / 3
+	}
+	prefix := a.KeyPrefix
+	if prefix == "" {
+		prefix = "snowflake/worker/"
+	}
+
+	for workerId := 0; workerId <= int(maxWorkerId); workerId++ {
+		key := prefix + strconv.Itoa(workerId)
+		acquired, err := a.Store.Acquire(key, lease.owner, lease.ttl)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrWorkerIdUnavailable, err)
+		}
+		if acquired {
+			lease.key = key
+			lease.workerId = uint16(workerId)
+			lease.renewedAt = time.Now()
+			go lease.keepAlive()
+			return lease, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: all %d worker ids are leased", ErrWorkerIdUnavailable, int(maxWorkerId)+1)
+}
+
+// workerIdLease 注册表中的机器码租约
+type workerIdLease struct {
+	store     LeaseStore
+	key       string
+	owner     string
+	workerId  uint16
+	ttl       time.Duration
+	interval  time.Duration
+	renewedAt time.Time
+
+	lost     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func (l *workerIdLease) WorkerId() uint16 {
+	return l.workerId
+}
+
+func (l *workerIdLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *workerIdLease) Release() error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	return l.store.Release(l.key, l.owner)
+}
+
+// keepAlive 定期续约，续约被拒绝或下一次续约前租约就会过期时判定为丢失
+func (l *workerIdLease) keepAlive() {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			renewed, err := l.store.Renew(l.key, l.owner, l.ttl)
+			if err == nil && renewed {
+				l.renewedAt = time.Now()
+				continue
+			}
+			if (err == nil && !renewed) || time.Since(l.renewedAt)+l.interval >= l.ttl {
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
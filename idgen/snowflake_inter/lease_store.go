@@ -0,0 +1,67 @@
+package snowflake
+
+import (
+	"sync"
+	"time"
+)
+
+// LeaseStore 租约注册表使用的键值存储，所有操作需保证原子性
+type LeaseStore interface {
+	// Acquire key 不存在或已过期时写入 owner 并设置 ttl，成功返回 true
+	Acquire(key, owner string, ttl time.Duration) (bool, error)
+	// Renew key 仍归属 owner 时续期，否则返回 false
+	Renew(key, owner string, ttl time.Duration) (bool, error)
+	// Release key 归属 owner 时删除
+	Release(key, owner string) error
+}
+
+type leaseRecord struct {
+	owner    string
+	expireAt time.Time
+}
+
+// MemoryLeaseStore 进程内租约存储，用于测试
+type MemoryLeaseStore struct {
+	sync.Mutex
+	records map[string]leaseRecord
+}
+
+// NewMemoryLeaseStore 创建进程内租约存储
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{records: make(map[string]leaseRecord)}
+}
+
+func (s *MemoryLeaseStore) Acquire(key, owner string, ttl time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	if record, exists := s.records[key]; exists && record.owner != owner && record.expireAt.After(now) {
+		return false, nil
+	}
+	s.records[key] = leaseRecord{owner: owner, expireAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryLeaseStore) Renew(key, owner string, ttl time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	record, exists := s.records[key]
+	if !exists || record.owner != owner || !record.expireAt.After(now) {
+		return false, nil
+	}
+	s.records[key] = leaseRecord{owner: owner, expireAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryLeaseStore) Release(key, owner string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if record, exists := s.records[key]; exists && record.owner == owner {
+		delete(s.records, key)
+	}
+	return nil
+}
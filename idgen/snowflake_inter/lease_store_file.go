@@ -0,0 +1,109 @@
+package snowflake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhlanshan/lotus/internal/fileutil"
+)
+
+// FileLeaseStore 基于文件锁的租约存储，同一主机上的多个进程可共享同一目录
+type FileLeaseStore struct {
+	dir string
+	mu  sync.Mutex // 文件锁只在进程间互斥，进程内额外加锁
+}
+
+// NewFileLeaseStore 创建租约存储，目录不存在时自动创建
+func NewFileLeaseStore(dir string) (*FileLeaseStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileLeaseStore{dir: dir}, nil
+}
+
+func (s *FileLeaseStore) Acquire(key, owner string, ttl time.Duration) (bool, error) {
+	var acquired bool
+	err := s.withLock(func() error {
+		now := time.Now()
+		record, exists, err := s.read(key)
+		if err != nil {
+			return err
+		}
+		if exists && record.owner != owner && record.expireAt.After(now) {
+			return nil
+		}
+		acquired = true
+		return s.write(key, leaseRecord{owner: owner, expireAt: now.Add(ttl)})
+	})
+	return acquired, err
+}
+
+func (s *FileLeaseStore) Renew(key, owner string, ttl time.Duration) (bool, error) {
+	var renewed bool
+	err := s.withLock(func() error {
+		now := time.Now()
+		record, exists, err := s.read(key)
+		if err != nil {
+			return err
+		}
+		if !exists || record.owner != owner || !record.expireAt.After(now) {
+			return nil
+		}
+		renewed = true
+		return s.write(key, leaseRecord{owner: owner, expireAt: now.Add(ttl)})
+	})
+	return renewed, err
+}
+
+func (s *FileLeaseStore) Release(key, owner string) error {
+	return s.withLock(func() error {
+		record, exists, err := s.read(key)
+		if err != nil || !exists || record.owner != owner {
+			return err
+		}
+		return os.Remove(s.path(key))
+	})
+}
+
+// withLock 持有目录锁执行 fn
+func (s *FileLeaseStore) withLock(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fileutil.WithLock(filepath.Join(s.dir, ".lock"), fn)
+}
+
+func (s *FileLeaseStore) path(key string) string {
+	return filepath.Join(s.dir, strings.NewReplacer("/", "_", "\\", "_").Replace(key)+".lease")
+}
+
+// read 读取租约记录，格式为 "owner\n过期时间(UnixNano)"
+func (s *FileLeaseStore) read(key string) (leaseRecord, bool, error) {
+	content, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return leaseRecord{}, false, nil
+	}
+	if err != nil {
+		return leaseRecord{}, false, err
+	}
+
+	owner, expireAt, found := strings.Cut(string(content), "\n")
+	if !found {
+		return leaseRecord{}, false, nil // 记录损坏视为不存在
+	}
+	nanos, err := strconv.ParseInt(expireAt, 10, 64)
+	if err != nil {
+		return leaseRecord{}, false, nil
+	}
+	return leaseRecord{owner: owner, expireAt: time.Unix(0, nanos)}, true, nil
+}
+
+// write 原子地写入租约记录，避免读到写了一半的记录
+func (s *FileLeaseStore) write(key string, record leaseRecord) error {
+	content := record.owner + "\n" + strconv.FormatInt(record.expireAt.UnixNano(), 10)
+	return fileutil.WriteFileAtomic(s.path(key), []byte(content))
+}
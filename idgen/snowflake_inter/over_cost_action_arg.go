@@ -1,19 +1,33 @@
 package snowflake
 
-type overCostActionArg struct {
-	ActionType             int32
-	TimeTick               int64
-	WorkerId               uint16
-	OverCostCountInOneTerm int32
-	GenCountInOneTerm      int32
-	TermIndex              int32
+// 事件类型
+const (
+	ActionBeginOverCost int32 = 1 // 开始漂移
+	ActionEndOverCost   int32 = 2 // 结束漂移
+	ActionBeginTurnBack int32 = 8 // 开始时间回拨
+	ActionEndTurnBack   int32 = 9 // 结束时间回拨
+)
+
+// OverCostActionArg 漂移/时间回拨事件参数
+type OverCostActionArg struct {
+	ActionType             int32  // 事件类型，见 Action 常量
+	TimeTick               int64  // 事件发生时使用的时间戳（相对 BaseTime）
+	WorkerId               uint16 // 机器码
+	OverCostCountInOneTerm int32  // 本轮漂移次数，回拨事件为 0
+	GenCountInOneTerm      int32  // 本轮漂移期间生成的 ID 数，回拨事件为 0
+	TermIndex              int32  // 漂移轮次，回拨事件为回拨序号（1-4）
 }
 
-func (oa overCostActionArg) OverCostActionArg(workerId uint16, timeTick int64, actionType int32, overCostCountInOneTerm int32, genCountWhenOverCost int32, index int32) {
-	oa.ActionType = actionType
-	oa.TimeTick = timeTick
-	oa.WorkerId = workerId
-	oa.OverCostCountInOneTerm = overCostCountInOneTerm
-	oa.GenCountInOneTerm = genCountWhenOverCost
-	oa.TermIndex = index
+// GenIdActionHandler 事件回调，在发号锁内同步调用，不能阻塞，需要异步处理时请转发到带缓冲的 channel
+type GenIdActionHandler func(arg OverCostActionArg)
+
+func newOverCostActionArg(workerId uint16, timeTick int64, actionType int32, overCostCountInOneTerm int32, genCountWhenOverCost int32, index int32) *OverCostActionArg {
+	return &OverCostActionArg{
+		ActionType:             actionType,
+		TimeTick:               timeTick,
+		WorkerId:               workerId,
+		OverCostCountInOneTerm: overCostCountInOneTerm,
+		GenCountInOneTerm:      genCountWhenOverCost,
+		TermIndex:              index,
+	}
 }
@@ -1,12 +1,28 @@
 package snowflake
 
-import "fmt"
+import "errors"
 
-type idGeneratorException struct {
-	message string
-	error   error
+var (
+	// ErrInvalidOptions 配置校验失败，可通过 errors.Is 判断
+	ErrInvalidOptions = errors.New("snowflake: invalid options")
+	// ErrClockMovedBackwards 时钟回拨，按 ClockSkewPolicy 无法继续发号
+	ErrClockMovedBackwards = errors.New("snowflake: clock moved backwards")
+)
+
+// OptionError 配置项校验错误，Field 为不合法的配置项名称
+type OptionError struct {
+	Field   string
+	Message string
+}
+
+func newOptionError(field, message string) *OptionError {
+	return &OptionError{Field: field, Message: message}
+}
+
+func (e *OptionError) Error() string {
+	return "snowflake: " + e.Message
 }
 
-func (e idGeneratorException) IdGeneratorException(message ...interface{}) {
-	fmt.Println(message)
+func (e *OptionError) Is(target error) bool {
+	return target == ErrInvalidOptions
 }
@@ -1,32 +1,61 @@
 package snowflake
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var singletonMutex sync.Mutex
-var idGenerator *defaultIdGenerator
+var idGenerator atomic.Pointer[DefaultIdGenerator]
 
-// setIdGenerator 设置生成器
-func setIdGenerator(options *idGeneratorOptions) {
+// SetIdGenerator 设置生成器，配置不合法时保留原生成器并返回错误。
+// 替换前先关闭原生成器，并等待时钟越过其最后发号的毫秒（漂移算法可能借用了未来时间），
+// 新生成器使用相同的机器码时也不会发出重复的 ID；等待期间 NextId 阻塞到替换完成
+func SetIdGenerator(options *IdGeneratorOptions) error {
 	singletonMutex.Lock()
-	idGenerator = newDefaultIdGenerator(options)
-	singletonMutex.Unlock()
+	defer singletonMutex.Unlock()
+
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		return err
+	}
+	if previous := idGenerator.Load(); previous != nil {
+		_ = previous.Close()
+		generator.waitPast(previous.lastIssued())
+	}
+	idGenerator.Store(generator)
+	return nil
 }
 
 func extractTime(id int64) time.Time {
-	return idGenerator.ExtractTime(id)
+	return idGenerator.Load().ExtractTime(id)
 }
 
 func NextId() int64 {
-	if idGenerator == nil {
-		panic("Please initialize Yitter.IdGeneratorOptions first.")
+	for {
+		generator := idGenerator.Load()
+		if generator == nil {
+			panic("Please initialize Yitter.IdGeneratorOptions first.")
+		}
+		id, err := generator.NextIdErr()
+		if errors.Is(err, ErrGeneratorClosed) {
+			// 生成器正在被 SetIdGenerator 替换，等待替换完成
+			singletonMutex.Lock()
+			singletonMutex.Unlock()
+			continue
+		}
+		if err != nil {
+			panic(err)
+		}
+		return id
 	}
-	return idGenerator.NewLong()
 }
 
 func init() {
-	var options = newIdGeneratorOptions(1)
-	setIdGenerator(options)
+	var options = NewIdGeneratorOptions(1)
+	if err := SetIdGenerator(options); err != nil {
+		panic(err)
+	}
 }
@@ -1,7 +1,30 @@
 package snowflake
 
-type idGeneratorOptions struct {
-	Method            uint16 // 雪花计算方法,（1-漂移算法|2-传统算法），默认1
+import (
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// 雪花计算方法
+const (
+	MethodDrift       uint16 = 1 // 漂移算法
+	MethodTraditional uint16 = 2 // 传统算法
+	MethodLockFree    uint16 = 3 // 无锁算法
+)
+
+// ClockSkewPolicy 传统算法（Method 2）和无锁算法（Method 3，回拨超出漂移上限时）的时钟回拨策略
+type ClockSkewPolicy uint8
+
+const (
+	ClockSkewTurnBack ClockSkewPolicy = iota // 使用漂移算法的回拨预留序列数（1-4）继续发号，默认
+	ClockSkewWait                            // 阻塞等待时钟追上，最多等待 MaxClockSkewWait
+	ClockSkewError                           // 直接返回 ErrClockMovedBackwards
+)
+
+// IdGeneratorOptions 雪花算法配置，建议通过 NewIdGeneratorOptions 获取默认值后再修改
+type IdGeneratorOptions struct {
+	Method            uint16 // 雪花计算方法,（1-漂移算法|2-传统算法|3-无锁算法，漂移上限为 TopOverCostCount），默认1
 	BaseTime          int64  // 基础时间（ms单位），不能超过当前系统时间
 	WorkerId          uint16 // 机器码，必须由外部设定，最大值 2^WorkerIdBitLength-1
 	WorkerIdBitLength byte   // 机器码位长，默认值6，取值范围 [1, 15]（要求：序列数位长+机器码位长不超过22）
@@ -9,10 +32,26 @@ type idGeneratorOptions struct {
 	MaxSeqNumber      uint32 // 最大序列数（含），设置范围 [MinSeqNumber, 2^SeqBitLength-1]，默认值0，表示最大序列数取最大值（2^SeqBitLength-1]）
 	MinSeqNumber      uint32 // 最小序列数（含），默认值5，取值范围 [5, MaxSeqNumber]，每毫秒的前5个序列数对应编号0-4是保留位，其中1-4是时间回拨相应预留位，0是手工新值预留位
 	TopOverCostCount  uint32 // 最大漂移次数（含），默认2000，推荐范围500-10000（与计算能力有关）
+
+	DataCenterId          uint16 // 数据中心 ID，最大值 2^DataCenterIdBitLength-1
+	DataCenterIdBitLength byte   // 数据中心 ID 位长，默认0（不区分数据中心），位于机器码之前（要求：标志位+数据中心位长+机器码位长+序列数位长不超过22）
+	ReserveFlagBit        bool   // 在时间戳之后保留 1 位标志位
+	Flag                  bool   // 标志位取值，ReserveFlagBit 为 true 时写入 ID
+
+	WorkerIdAssigner   WorkerIdAssigner   // 机器码分配器，设置后忽略 WorkerId，由分配器在创建生成器时决定
+	GenIdActionHandler GenIdActionHandler // 漂移/时间回拨事件回调，漂移算法（Method 1）及传统算法的回拨预留位策略触发
+	ClockSkewPolicy    ClockSkewPolicy    // 传统算法和无锁算法的时钟回拨策略，默认 ClockSkewTurnBack
+	MaxClockSkewWait   time.Duration      // ClockSkewWait 策略的最大等待时间，默认 1s
+	Clock              timeutil.Clock     // 时钟，默认系统时钟，测试时可注入
+
+	CheckpointStore    CheckpointStore // 检查点存储，设置后定期续期检查点，发出的 ID 不会晚于已保存的检查点，启动时不会发出早于检查点的 ID
+	CheckpointInterval time.Duration   // 检查点保存间隔，默认 1s
+	CheckpointMaxWait  time.Duration   // 启动时当前时间未越过检查点的最大等待时间，默认0，即立即返回 ErrClockMovedBackwards；漂移算法另外允许等待 TopOverCostCount 毫秒
 }
 
-func newIdGeneratorOptions(workerId uint16) *idGeneratorOptions {
-	return &idGeneratorOptions{
+// NewIdGeneratorOptions 返回指定机器码的默认配置
+func NewIdGeneratorOptions(workerId uint16) *IdGeneratorOptions {
+	return &IdGeneratorOptions{
 		Method:            1,
 		WorkerId:          workerId,
 		BaseTime:          1582136402000,
@@ -23,3 +62,16 @@ func newIdGeneratorOptions(workerId uint16) *idGeneratorOptions {
 		TopOverCostCount:  2000,
 	}
 }
+
+// flagBitLength 标志位位长
+func (o *IdGeneratorOptions) flagBitLength() byte {
+	if o.ReserveFlagBit {
+		return 1
+	}
+	return 0
+}
+
+// timestampShift 时间戳之后的总位长：[时间戳][标志位][数据中心][机器码][序列数]
+func (o *IdGeneratorOptions) timestampShift() byte {
+	return o.flagBitLength() + o.DataCenterIdBitLength + o.WorkerIdBitLength + o.SeqBitLength
+}
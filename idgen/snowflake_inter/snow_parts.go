@@ -0,0 +1,94 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidId ID 与生成器配置不匹配，可通过 errors.Is 判断
+var ErrInvalidId = errors.New("snowflake: invalid id")
+
+// SnowflakeParts 雪花 ID 的组成部分
+type SnowflakeParts struct {
+	Time         time.Time // 时间戳对应的时间（BaseTime + TimeTick）
+	TimeTick     int64     // 相对 BaseTime 的毫秒数
+	Flag         bool      // 标志位，ReserveFlagBit 为 false 时恒为 false
+	DataCenterId uint16    // 数据中心 ID，DataCenterIdBitLength 为 0 时恒为 0
+	WorkerId     uint16    // 机器码
+	SeqNumber    uint32    // 序列数
+	IsTurnBack   bool      // 序列数为 1-4，时间回拨期间生成
+	IsOverCost   bool      // 时间戳超前于当前时间，漂移期间借用了未来时间（该时间到来后无法再判定）
+}
+
+// Decompose 按生成器配置的位长和 BaseTime 拆解 ID
+func (dig *DefaultIdGenerator) Decompose(id int64) (SnowflakeParts, error) {
+	if id < 0 {
+		return SnowflakeParts{}, fmt.Errorf("%w: negative id %d", ErrInvalidId, id)
+	}
+
+	options := dig.Options
+	seqMask := int64(1)<<options.SeqBitLength - 1
+	workerMask := int64(1)<<options.WorkerIdBitLength - 1
+	dataCenterMask := int64(1)<<options.DataCenterIdBitLength - 1
+	dataCenterShift := options.WorkerIdBitLength + options.SeqBitLength
+	timestampShift := options.timestampShift()
+	timeTick := id >> timestampShift
+
+	parts := SnowflakeParts{
+		Time:         time.UnixMilli(timeTick + options.BaseTime),
+		TimeTick:     timeTick,
+		Flag:         options.ReserveFlagBit && id>>(timestampShift-1)&1 == 1,
+		DataCenterId: uint16(id >> dataCenterShift & dataCenterMask),
+		WorkerId:     uint16(id >> options.SeqBitLength & workerMask),
+		SeqNumber:    uint32(id & seqMask),
+	}
+	if options.MaxSeqNumber > 0 && parts.SeqNumber > options.MaxSeqNumber {
+		return parts, fmt.Errorf("%w: sequence %d exceeds MaxSeqNumber %d", ErrInvalidId, parts.SeqNumber, options.MaxSeqNumber)
+	}
+	// 序列数 0 保留不用，1-4 用于时间回拨，其余从 MinSeqNumber 开始
+	if parts.SeqNumber == 0 || parts.SeqNumber > 4 && parts.SeqNumber < options.MinSeqNumber {
+		return parts, fmt.Errorf("%w: sequence %d is never issued (MinSeqNumber %d)", ErrInvalidId, parts.SeqNumber, options.MinSeqNumber)
+	}
+	parts.IsTurnBack = parts.SeqNumber >= 1 && parts.SeqNumber <= 4
+	parts.IsOverCost = parts.Time.After(dig.clock.Now())
+	return parts, nil
+}
+
+// Compose 按生成器配置组装 ID，Time 非零时优先于 TimeTick，常用于测试构造 ID
+func (dig *DefaultIdGenerator) Compose(parts SnowflakeParts) (int64, error) {
+	options := dig.Options
+	timeTick := parts.TimeTick
+	if !parts.Time.IsZero() {
+		timeTick = parts.Time.UnixMilli() - options.BaseTime
+	}
+
+	timestampShift := options.timestampShift()
+	if timeTick < 0 || timeTick > int64(1)<<(63-timestampShift)-1 {
+		return 0, fmt.Errorf("%w: time tick %d out of range", ErrInvalidId, timeTick)
+	}
+	if parts.Flag && !options.ReserveFlagBit {
+		return 0, fmt.Errorf("%w: flag bit is not reserved", ErrInvalidId)
+	}
+	if int64(parts.DataCenterId) > int64(1)<<options.DataCenterIdBitLength-1 {
+		return 0, fmt.Errorf("%w: data center id %d out of range", ErrInvalidId, parts.DataCenterId)
+	}
+	if int64(parts.WorkerId) > int64(1)<<options.WorkerIdBitLength-1 {
+		return 0, fmt.Errorf("%w: worker id %d out of range", ErrInvalidId, parts.WorkerId)
+	}
+	if int64(parts.SeqNumber) > int64(1)<<options.SeqBitLength-1 {
+		return 0, fmt.Errorf("%w: sequence %d out of range", ErrInvalidId, parts.SeqNumber)
+	}
+
+	id := timeTick<<timestampShift | int64(parts.DataCenterId)<<(options.WorkerIdBitLength+options.SeqBitLength) |
+		int64(parts.WorkerId)<<options.SeqBitLength | int64(parts.SeqNumber)
+	if parts.Flag {
+		id |= int64(1) << (timestampShift - 1)
+	}
+	return id, nil
+}
+
+// Decompose 使用默认生成器的配置拆解 ID
+func Decompose(id int64) (SnowflakeParts, error) {
+	return idGenerator.Load().Decompose(id)
+}
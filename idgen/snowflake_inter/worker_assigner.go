@@ -0,0 +1,121 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrWorkerIdUnavailable 无法分配机器码
+	ErrWorkerIdUnavailable = errors.New("snowflake: worker id unavailable")
+	// ErrLeaseLost 机器码租约已丢失，生成器停止发号
+	ErrLeaseLost = errors.New("snowflake: worker id lease lost")
+	// ErrGeneratorClosed 生成器已关闭
+	ErrGeneratorClosed = errors.New("snowflake: generator closed")
+)
+
+// WorkerIdAssigner 机器码分配器，生成器创建时调用一次
+type WorkerIdAssigner interface {
+	// Assign 分配一个不超过 maxWorkerId 的机器码
+	Assign(maxWorkerId uint16) (WorkerIdLease, error)
+}
+
+// WorkerIdLease 已分配的机器码
+type WorkerIdLease interface {
+	WorkerId() uint16
+	// Lost 租约丢失时关闭，永不丢失的租约返回 nil
+	Lost() <-chan struct{}
+	// Release 释放机器码
+	Release() error
+}
+
+// staticLease 永不丢失的租约
+type staticLease uint16
+
+func (l staticLease) WorkerId() uint16 {
+	return uint16(l)
+}
+
+func (l staticLease) Lost() <-chan struct{} {
+	return nil
+}
+
+func (l staticLease) Release() error {
+	return nil
+}
+
+// StaticAssigner 固定机器码
+type StaticAssigner uint16
+
+func (a StaticAssigner) Assign(maxWorkerId uint16) (WorkerIdLease, error) {
+	if uint16(a) > maxWorkerId {
+		return nil, fmt.Errorf("%w: static worker id %d exceeds %d", ErrWorkerIdUnavailable, a, maxWorkerId)
+	}
+	return staticLease(a), nil
+}
+
+// EnvAssigner 从环境变量读取机器码，环境变量为空时解析主机名的序号后缀（StatefulSet 风格，如 web-3）
+type EnvAssigner struct {
+	EnvName  string                 // 环境变量名，为空时只解析主机名
+	Offset   uint16                 // 机器码偏移量，多个 StatefulSet 共享位长时使用
+	Hostname func() (string, error) // 获取主机名，默认 os.Hostname
+}
+
+// NewEnvAssigner 创建读取指定环境变量的分配器
+func NewEnvAssigner(envName string) *EnvAssigner {
+	return &EnvAssigner{EnvName: envName}
+}
+
+func (a *EnvAssigner) Assign(maxWorkerId uint16) (WorkerIdLease, error) {
+	var ordinal uint64
+	if value := a.lookupEnv(); value != "" {
+		parsed, err := strconv.ParseUint(strings.TrimSpace(value), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse %s=%q: %v", ErrWorkerIdUnavailable, a.EnvName, value, err)
+		}
+		ordinal = parsed
+	} else {
+		hostname := a.Hostname
+		if hostname == nil {
+			hostname = os.Hostname
+		}
+		name, err := hostname()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrWorkerIdUnavailable, err)
+		}
+		parsed, err := parseHostnameOrdinal(name)
+		if err != nil {
+			return nil, err
+		}
+		ordinal = parsed
+	}
+
+	workerId := ordinal + uint64(a.Offset)
+	if workerId > uint64(maxWorkerId) {
+		return nil, fmt.Errorf("%w: worker id %d exceeds %d", ErrWorkerIdUnavailable, workerId, maxWorkerId)
+	}
+	return staticLease(workerId), nil
+}
+
+func (a *EnvAssigner) lookupEnv() string {
+	if a.EnvName == "" {
+		return ""
+	}
+	return os.Getenv(a.EnvName)
+}
+
+// parseHostnameOrdinal 解析主机名最后一个 "-" 之后的序号
+func parseHostnameOrdinal(hostname string) (uint64, error) {
+	index := strings.LastIndexByte(hostname, '-')
+	if index < 0 || index == len(hostname)-1 {
+		return 0, fmt.Errorf("%w: hostname %q has no ordinal suffix", ErrWorkerIdUnavailable, hostname)
+	}
+	ordinal, err := strconv.ParseUint(hostname[index+1:], 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%w: hostname %q has no ordinal suffix", ErrWorkerIdUnavailable, hostname)
+	}
+	return ordinal, nil
+}
@@ -0,0 +1,117 @@
+package snowflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试固定机器码与环境变量分配器
+func TestStaticAndEnvAssigner(t *testing.T) {
+	lease, err := StaticAssigner(9).Assign(63)
+	if err != nil || lease.WorkerId() != 9 || lease.Lost() != nil {
+		t.Errorf("StaticAssigner(9) = %v, %v", lease, err)
+	}
+	if _, err = StaticAssigner(64).Assign(63); !errors.Is(err, ErrWorkerIdUnavailable) {
+		t.Errorf("expected ErrWorkerIdUnavailable, got %v", err)
+	}
+
+	t.Setenv("LOTUS_WORKER_ID", "12")
+	assigner := NewEnvAssigner("LOTUS_WORKER_ID")
+	if lease, err = assigner.Assign(63); err != nil || lease.WorkerId() != 12 {
+		t.Errorf("env assigner = %v, %v; want 12", lease, err)
+	}
+
+	t.Setenv("LOTUS_WORKER_ID", "")
+	assigner.Hostname = func() (string, error) { return "id-service-4", nil }
+	assigner.Offset = 10
+	if lease, err = assigner.Assign(63); err != nil || lease.WorkerId() != 14 {
+		t.Errorf("hostname assigner = %v, %v; want 14", lease, err)
+	}
+
+	for _, hostname := range []string{"localhost", "web-", "web-x", "web-70000"} {
+		if _, err = parseHostnameOrdinal(hostname); !errors.Is(err, ErrWorkerIdUnavailable) {
+			t.Errorf("parseHostnameOrdinal(%q): expected ErrWorkerIdUnavailable, got %v", hostname, err)
+		}
+	}
+}
+
+// 测试租约分配器分配互不重复的机器码
+func TestLeaseAssigner(t *testing.T) {
+	stores := map[string]LeaseStore{"memory": NewMemoryLeaseStore()}
+	fileStore, err := NewFileLeaseStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	stores["file"] = fileStore
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			var leases []WorkerIdLease
+			for i := 0; i < 4; i++ {
+				lease, err := NewLeaseAssigner(store, time.Minute).Assign(3)
+				if err != nil {
+					t.Fatalf("assign %d: unexpected error %v", i, err)
+				}
+				if lease.WorkerId() != uint16(i) {
+					t.Errorf("expected worker id %d, got %d", i, lease.WorkerId())
+				}
+				leases = append(leases, lease)
+			}
+
+			if _, err := NewLeaseAssigner(store, time.Minute).Assign(3); !errors.Is(err, ErrWorkerIdUnavailable) {
+				t.Errorf("expected ErrWorkerIdUnavailable, got %v", err)
+			}
+
+			if err := leases[2].Release(); err != nil {
+				t.Fatalf("release: unexpected error %v", err)
+			}
+			lease, err := NewLeaseAssigner(store, time.Minute).Assign(3)
+			if err != nil || lease.WorkerId() != 2 {
+				t.Errorf("expected released worker id 2, got %v, %v", lease, err)
+			}
+
+			for _, lease := range append(leases, lease) {
+				_ = lease.Release()
+			}
+		})
+	}
+}
+
+// 测试租约丢失后生成器停止发号
+func TestGeneratorStopsWhenLeaseLost(t *testing.T) {
+	store := NewMemoryLeaseStore()
+	options := NewIdGeneratorOptions(0)
+	options.WorkerIdAssigner = &LeaseAssigner{Store: store, TTL: 300 * time.Millisecond, RenewInterval: 20 * time.Millisecond}
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer generator.Close()
+
+	time.Sleep(100 * time.Millisecond) // 经过多次续约
+	if _, err = generator.NextIdErr(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 模拟租约被其他节点抢占
+	store.Lock()
+	store.records["snowflake/worker/0"] = leaseRecord{owner: "other", expireAt: time.Now().Add(time.Hour)}
+	store.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err = generator.NextIdErr(); errors.Is(err, ErrLeaseLost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected ErrLeaseLost, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	_ = generator.Close()
+	if _, err = generator.NextIdErr(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("lost lease should take precedence after close, got %v", err)
+	}
+}
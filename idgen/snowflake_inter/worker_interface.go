@@ -1,5 +1,7 @@
 package snowflake
 
 type iSnowWorker interface {
-	NextId() int64
+	NextId() (int64, error)
+	NextIds(ids []int64) error
+	LastTimeTick() int64
 }
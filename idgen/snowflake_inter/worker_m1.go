@@ -1,8 +1,11 @@
 package snowflake
 
 import (
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
 )
 
 // SnowWorkerM1 .
@@ -14,7 +17,10 @@ type snowWorkerM1 struct {
 	MaxSeqNumber      uint32 // 最大序列数（含）
 	MinSeqNumber      uint32 // 最小序列数（含）
 	TopOverCostCount  uint32 // 最大漂移次数
+	ClockSkewPolicy   ClockSkewPolicy
+	MaxClockSkewWait  time.Duration
 	_TimestampShift   byte
+	_NodeBits         int64 // 已移位的标志位、数据中心 ID 和机器码
 	_CurrentSeqNumber uint32
 
 	_LastTimeTick           int64
@@ -22,12 +28,17 @@ type snowWorkerM1 struct {
 	_TurnBackIndex          byte
 	_IsOverCost             bool
 	_OverCostCountInOneTerm uint32
+	_GenCountInOneTerm      uint32
+	_TermIndex              uint32
+
+	_GenIdActionHandler GenIdActionHandler
+	_Clock              timeutil.Clock
 
 	sync.Mutex
 }
 
 // newSnowWorkerM1 .
-func newSnowWorkerM1(options *idGeneratorOptions) iSnowWorker {
+func newSnowWorkerM1(options *IdGeneratorOptions) iSnowWorker {
 	var workerIdBitLength byte
 	var seqBitLength byte
 	var maxSeqNumber uint32
@@ -70,8 +81,23 @@ func newSnowWorkerM1(options *idGeneratorOptions) iSnowWorker {
 	// 7.TopOverCostCount
 	var topOverCostCount = options.TopOverCostCount
 
-	// 8.Others
-	timestampShift := (byte)(workerIdBitLength + seqBitLength)
+	// 8.Clock
+	var clock = options.Clock
+	if clock == nil {
+		clock = timeutil.RealClock{}
+	}
+	var maxClockSkewWait = options.MaxClockSkewWait
+	if maxClockSkewWait <= 0 {
+		maxClockSkewWait = time.Second
+	}
+
+	// 9.Others
+	flagBitLength := options.flagBitLength()
+	timestampShift := (byte)(flagBitLength + options.DataCenterIdBitLength + workerIdBitLength + seqBitLength)
+	var nodeBits = int64(options.DataCenterId)<<(workerIdBitLength+seqBitLength) | int64(workerId)<<seqBitLength
+	if options.ReserveFlagBit && options.Flag {
+		nodeBits |= int64(1) << (timestampShift - 1)
+	}
 	currentSeqNumber := minSeqNumber
 
 	return &snowWorkerM1{
@@ -82,7 +108,10 @@ func newSnowWorkerM1(options *idGeneratorOptions) iSnowWorker {
 		MaxSeqNumber:      maxSeqNumber,
 		MinSeqNumber:      minSeqNumber,
 		TopOverCostCount:  topOverCostCount,
+		ClockSkewPolicy:   options.ClockSkewPolicy,
+		MaxClockSkewWait:  maxClockSkewWait,
 		_TimestampShift:   timestampShift,
+		_NodeBits:         nodeBits,
 		_CurrentSeqNumber: currentSeqNumber,
 
 		_LastTimeTick:           0,
@@ -90,48 +119,68 @@ func newSnowWorkerM1(options *idGeneratorOptions) iSnowWorker {
 		_TurnBackIndex:          0,
 		_IsOverCost:             false,
 		_OverCostCountInOneTerm: 0,
+		_GenCountInOneTerm:      0,
+		_TermIndex:              0,
+
+		_GenIdActionHandler: options.GenIdActionHandler,
+		_Clock:              clock,
 	}
 }
 
 // DoGenIdAction .
-func (m1 *snowWorkerM1) DoGenIdAction(arg *overCostActionArg) {
-
+func (m1 *snowWorkerM1) DoGenIdAction(arg *OverCostActionArg) {
+	if m1._GenIdActionHandler == nil {
+		return
+	}
+	m1._GenIdActionHandler(*arg)
 }
 
 func (m1 *snowWorkerM1) BeginOverCostAction(useTimeTick int64) {
-
+	if m1._GenIdActionHandler == nil {
+		return
+	}
+	m1.DoGenIdAction(newOverCostActionArg(m1.WorkerId, useTimeTick, ActionBeginOverCost, int32(m1._OverCostCountInOneTerm), int32(m1._GenCountInOneTerm), int32(m1._TermIndex)))
 }
 
 func (m1 *snowWorkerM1) EndOverCostAction(useTimeTick int64) {
-
+	if m1._GenIdActionHandler == nil {
+		return
+	}
+	m1.DoGenIdAction(newOverCostActionArg(m1.WorkerId, useTimeTick, ActionEndOverCost, int32(m1._OverCostCountInOneTerm), int32(m1._GenCountInOneTerm), int32(m1._TermIndex)))
 }
 
 func (m1 *snowWorkerM1) BeginTurnBackAction(useTimeTick int64) {
-
+	if m1._GenIdActionHandler == nil {
+		return
+	}
+	m1.DoGenIdAction(newOverCostActionArg(m1.WorkerId, useTimeTick, ActionBeginTurnBack, 0, 0, int32(m1._TurnBackIndex)))
 }
 
 func (m1 *snowWorkerM1) EndTurnBackAction(useTimeTick int64) {
-
+	if m1._GenIdActionHandler == nil {
+		return
+	}
+	m1.DoGenIdAction(newOverCostActionArg(m1.WorkerId, useTimeTick, ActionEndTurnBack, 0, 0, int32(m1._TurnBackIndex)))
 }
 
 func (m1 *snowWorkerM1) NextOverCostId() int64 {
 	currentTimeTick := m1.GetCurrentTimeTick()
 	if currentTimeTick > m1._LastTimeTick {
-		// m1.EndOverCostAction(currentTimeTick)
+		m1.EndOverCostAction(currentTimeTick)
 		m1._LastTimeTick = currentTimeTick
 		m1._CurrentSeqNumber = m1.MinSeqNumber
 		m1._IsOverCost = false
 		m1._OverCostCountInOneTerm = 0
-		// m1._GenCountInOneTerm = 0
+		m1._GenCountInOneTerm = 0
 		return m1.CalcId(m1._LastTimeTick)
 	}
 	if m1._OverCostCountInOneTerm >= m1.TopOverCostCount {
-		// m1.EndOverCostAction(currentTimeTick)
+		m1.EndOverCostAction(currentTimeTick)
 		m1._LastTimeTick = m1.GetNextTimeTick()
 		m1._CurrentSeqNumber = m1.MinSeqNumber
 		m1._IsOverCost = false
 		m1._OverCostCountInOneTerm = 0
-		// m1._GenCountInOneTerm = 0
+		m1._GenCountInOneTerm = 0
 		return m1.CalcId(m1._LastTimeTick)
 	}
 	if m1._CurrentSeqNumber > m1.MaxSeqNumber {
@@ -139,12 +188,12 @@ func (m1 *snowWorkerM1) NextOverCostId() int64 {
 		m1._CurrentSeqNumber = m1.MinSeqNumber
 		m1._IsOverCost = true
 		m1._OverCostCountInOneTerm++
-		// m1._GenCountInOneTerm++
+		m1._GenCountInOneTerm++
 
 		return m1.CalcId(m1._LastTimeTick)
 	}
 
-	// m1._GenCountInOneTerm++
+	m1._GenCountInOneTerm++
 	return m1.CalcId(m1._LastTimeTick)
 }
 
@@ -152,19 +201,7 @@ func (m1 *snowWorkerM1) NextOverCostId() int64 {
 func (m1 *snowWorkerM1) NextNormalId() int64 {
 	currentTimeTick := m1.GetCurrentTimeTick()
 	if currentTimeTick < m1._LastTimeTick {
-		if m1._TurnBackTimeTick < 1 {
-			m1._TurnBackTimeTick = m1._LastTimeTick - 1
-			m1._TurnBackIndex++
-			// 每毫秒序列数的前5位是预留位，0用于手工新值，1-4是时间回拨次序
-			// 支持4次回拨次序（避免回拨重叠导致ID重复），可无限次回拨（次序循环使用）。
-			if m1._TurnBackIndex > 4 {
-				m1._TurnBackIndex = 1
-			}
-			m1.BeginTurnBackAction(m1._TurnBackTimeTick)
-		}
-
-		// time.Sleep(time.Duration(1) * time.Millisecond)
-		return m1.CalcTurnBackId(m1._TurnBackTimeTick)
+		return m1.NextTurnBackId()
 	}
 
 	// 时间追平时，_TurnBackTimeTick清零
@@ -180,13 +217,17 @@ func (m1 *snowWorkerM1) NextNormalId() int64 {
 	}
 
 	if m1._CurrentSeqNumber > m1.MaxSeqNumber {
-		m1.BeginOverCostAction(currentTimeTick)
-		// m1._TermIndex++
+		// 先进入新一轮漂移再触发开始事件，使开始与结束事件携带相同的轮次
+		m1._TermIndex++
+		if m1._TermIndex > 10000 {
+			m1._TermIndex = 1
+		}
 		m1._LastTimeTick++
 		m1._CurrentSeqNumber = m1.MinSeqNumber
 		m1._IsOverCost = true
 		m1._OverCostCountInOneTerm = 1
-		// m1._GenCountInOneTerm = 1
+		m1._GenCountInOneTerm = 1
+		m1.BeginOverCostAction(currentTimeTick)
 
 		return m1.CalcId(m1._LastTimeTick)
 	}
@@ -194,23 +235,40 @@ func (m1 *snowWorkerM1) NextNormalId() int64 {
 	return m1.CalcId(m1._LastTimeTick)
 }
 
+// NextTurnBackId 时间回拨期间使用预留序列数生成 ID
+func (m1 *snowWorkerM1) NextTurnBackId() int64 {
+	if m1._TurnBackTimeTick < 1 {
+		m1._TurnBackTimeTick = m1._LastTimeTick - 1
+		m1._TurnBackIndex++
+		// 每毫秒序列数的前5位是预留位，0用于手工新值，1-4是时间回拨次序
+		// 支持4次回拨次序（避免回拨重叠导致ID重复），可无限次回拨（次序循环使用）。
+		if m1._TurnBackIndex > 4 {
+			m1._TurnBackIndex = 1
+		}
+		m1.BeginTurnBackAction(m1._TurnBackTimeTick)
+	}
+
+	// time.Sleep(time.Duration(1) * time.Millisecond)
+	return m1.CalcTurnBackId(m1._TurnBackTimeTick)
+}
+
 // CalcId .
 func (m1 *snowWorkerM1) CalcId(useTimeTick int64) int64 {
-	result := int64(useTimeTick<<m1._TimestampShift) + int64(m1.WorkerId<<m1.SeqBitLength) + int64(m1._CurrentSeqNumber)
+	result := int64(useTimeTick<<m1._TimestampShift) + m1._NodeBits + int64(m1._CurrentSeqNumber)
 	m1._CurrentSeqNumber++
 	return result
 }
 
 // CalcTurnBackId .
 func (m1 *snowWorkerM1) CalcTurnBackId(useTimeTick int64) int64 {
-	result := int64(useTimeTick<<m1._TimestampShift) + int64(m1.WorkerId<<m1.SeqBitLength) + int64(m1._TurnBackIndex)
+	result := int64(useTimeTick<<m1._TimestampShift) + m1._NodeBits + int64(m1._TurnBackIndex)
 	m1._TurnBackTimeTick--
 	return result
 }
 
 // GetCurrentTimeTick .
 func (m1 *snowWorkerM1) GetCurrentTimeTick() int64 {
-	var millis = time.Now().UnixNano() / 1e6
+	var millis = m1._Clock.Now().UnixNano() / 1e6
 	return millis - m1.BaseTime
 }
 
@@ -218,16 +276,56 @@ func (m1 *snowWorkerM1) GetCurrentTimeTick() int64 {
 func (m1 *snowWorkerM1) GetNextTimeTick() int64 {
 	tempTimeTicker := m1.GetCurrentTimeTick()
 	for tempTimeTicker <= m1._LastTimeTick {
-		time.Sleep(time.Duration(1) * time.Millisecond)
+		m1._Clock.Sleep(time.Duration(1) * time.Millisecond)
 		tempTimeTicker = m1.GetCurrentTimeTick()
 	}
 	return tempTimeTicker
 }
 
+// WaitClockSkew 时钟回拨时等待时钟追上 _LastTimeTick，超过 MaxClockSkewWait 返回错误
+func (m1 *snowWorkerM1) WaitClockSkew() (int64, error) {
+	currentTimeTick := m1.GetCurrentTimeTick()
+	deadline := m1._Clock.Now().Add(m1.MaxClockSkewWait)
+	for currentTimeTick < m1._LastTimeTick {
+		skew := time.Duration(m1._LastTimeTick-currentTimeTick) * time.Millisecond
+		if m1._Clock.Now().Add(skew).After(deadline) {
+			return 0, fmt.Errorf("%w: %d milliseconds exceeds max wait %v", ErrClockMovedBackwards, skew.Milliseconds(), m1.MaxClockSkewWait)
+		}
+		m1._Clock.Sleep(skew)
+		currentTimeTick = m1.GetCurrentTimeTick()
+	}
+	return currentTimeTick, nil
+}
+
 // NextId .
-func (m1 *snowWorkerM1) NextId() int64 {
+func (m1 *snowWorkerM1) NextId() (int64, error) {
+	m1.Lock()
+	defer m1.Unlock()
+	return m1.nextId(), nil
+}
+
+// NextIds 一次加锁填充 ids，当前毫秒剩余的序列数直接分配，用完后再按 NextId 的规则跨毫秒或漂移
+func (m1 *snowWorkerM1) NextIds(ids []int64) error {
 	m1.Lock()
 	defer m1.Unlock()
+	for i := 0; i < len(ids); {
+		ids[i] = m1.nextId()
+		i++
+		for ; i < len(ids) && !m1._IsOverCost && m1._TurnBackTimeTick == 0 && m1._CurrentSeqNumber <= m1.MaxSeqNumber; i++ {
+			ids[i] = m1.CalcId(m1._LastTimeTick)
+		}
+	}
+	return nil
+}
+
+// LastTimeTick 最后发出 ID 的时间戳（含漂移借用的未来时间）
+func (m1 *snowWorkerM1) LastTimeTick() int64 {
+	m1.Lock()
+	defer m1.Unlock()
+	return m1._LastTimeTick
+}
+
+func (m1 *snowWorkerM1) nextId() int64 {
 	if m1._IsOverCost {
 		return m1.NextOverCostId()
 	} else {
@@ -0,0 +1,68 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试漂移事件回调
+func TestSnowWorkerM1_OverCostActions(t *testing.T) {
+	var actions []OverCostActionArg
+	options := NewIdGeneratorOptions(5)
+	options.SeqBitLength = 3 // 每毫秒仅 3 个序列数（5-7），快速进入漂移
+	options.GenIdActionHandler = func(arg OverCostActionArg) {
+		actions = append(actions, arg)
+	}
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		generator.NextId()
+	}
+	time.Sleep(150 * time.Millisecond) // 时间追上漂移后结束本轮漂移
+	generator.NextId()
+
+	if len(actions) < 2 {
+		t.Fatalf("expected begin and end over cost actions, got %+v", actions)
+	}
+	begin, end := actions[0], actions[len(actions)-1]
+	if begin.ActionType != ActionBeginOverCost || begin.WorkerId != 5 || begin.TermIndex != 1 ||
+		begin.OverCostCountInOneTerm != 1 || begin.GenCountInOneTerm != 1 {
+		t.Errorf("unexpected begin action %+v", begin)
+	}
+	if end.ActionType != ActionEndOverCost || end.TermIndex != begin.TermIndex || end.OverCostCountInOneTerm == 0 || end.GenCountInOneTerm == 0 {
+		t.Errorf("unexpected end action %+v", end)
+	}
+}
+
+// 测试时间回拨事件回调
+func TestSnowWorkerM1_TurnBackActions(t *testing.T) {
+	var actions []OverCostActionArg
+	options := NewIdGeneratorOptions(5)
+	options.GenIdActionHandler = func(arg OverCostActionArg) {
+		actions = append(actions, arg)
+	}
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	worker := generator.SnowWorker.(*snowWorkerM1)
+
+	generator.NextId()
+	worker._LastTimeTick += 1000 // 模拟时钟回拨 1 秒
+	id := generator.NextId()
+	worker._LastTimeTick -= 1000 // 模拟时钟追平
+	generator.NextId()
+
+	if parts, _ := generator.Decompose(id); !parts.IsTurnBack {
+		t.Errorf("expected turn back id, got %+v", parts)
+	}
+	if len(actions) != 2 || actions[0].ActionType != ActionBeginTurnBack || actions[1].ActionType != ActionEndTurnBack {
+		t.Fatalf("expected begin and end turn back actions, got %+v", actions)
+	}
+	if actions[0].TermIndex != 1 {
+		t.Errorf("expected turn back index 1, got %d", actions[0].TermIndex)
+	}
+}
@@ -1,24 +1,59 @@
 package snowflake
 
-import (
-	"fmt"
-	"strconv"
-)
+import "fmt"
 
 type snowWorkerM2 struct {
 	*snowWorkerM1
 }
 
-func newSnowWorkerM2(options *idGeneratorOptions) iSnowWorker {
+func newSnowWorkerM2(options *IdGeneratorOptions) iSnowWorker {
 	return &snowWorkerM2{
 		newSnowWorkerM1(options).(*snowWorkerM1),
 	}
 }
 
-func (m2 snowWorkerM2) NextId() int64 {
+func (m2 snowWorkerM2) NextId() (int64, error) {
 	m2.Lock()
 	defer m2.Unlock()
+	return m2.nextId()
+}
+
+// NextIds 一次加锁填充 ids，出错时已填充的部分仍然有效
+func (m2 snowWorkerM2) NextIds(ids []int64) error {
+	m2.Lock()
+	defer m2.Unlock()
+	for i := range ids {
+		id, err := m2.nextId()
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+	return nil
+}
+
+func (m2 snowWorkerM2) nextId() (int64, error) {
 	currentTimeTick := m2.GetCurrentTimeTick()
+	if currentTimeTick < m2._LastTimeTick {
+		switch m2.ClockSkewPolicy {
+		case ClockSkewError:
+			return 0, fmt.Errorf("%w: %d milliseconds", ErrClockMovedBackwards, m2._LastTimeTick-currentTimeTick)
+		case ClockSkewWait:
+			var err error
+			if currentTimeTick, err = m2.WaitClockSkew(); err != nil {
+				return 0, err
+			}
+		default:
+			return m2.NextTurnBackId(), nil
+		}
+	}
+
+	// 时间追平时，_TurnBackTimeTick清零
+	if m2._TurnBackTimeTick > 0 {
+		m2.EndTurnBackAction(m2._TurnBackTimeTick)
+		m2._TurnBackTimeTick = 0
+	}
+
 	if m2._LastTimeTick == currentTimeTick {
 		m2._CurrentSeqNumber++
 		if m2._CurrentSeqNumber > m2.MaxSeqNumber {
@@ -28,10 +63,7 @@ func (m2 snowWorkerM2) NextId() int64 {
 	} else {
 		m2._CurrentSeqNumber = m2.MinSeqNumber
 	}
-	if currentTimeTick < m2._LastTimeTick {
-		fmt.Println("Time error for {0} milliseconds", strconv.FormatInt(m2._LastTimeTick-currentTimeTick, 10))
-	}
 	m2._LastTimeTick = currentTimeTick
-	result := int64(currentTimeTick<<m2._TimestampShift) + int64(m2.WorkerId<<m2.SeqBitLength) + int64(m2._CurrentSeqNumber)
-	return result
+	result := int64(currentTimeTick<<m2._TimestampShift) + m2._NodeBits + int64(m2._CurrentSeqNumber)
+	return result, nil
 }
@@ -0,0 +1,130 @@
+package snowflake
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// manualClock 手动推进的时钟，Sleep 不阻塞而是直接推进时间
+type manualClock struct {
+	timeutil.RealClock
+	sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Now()}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Sleep(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func (c *manualClock) Add(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newM2Generator(t *testing.T, clock *manualClock, policy ClockSkewPolicy) *DefaultIdGenerator {
+	options := NewIdGeneratorOptions(2)
+	options.Method = 2
+	options.Clock = clock
+	options.ClockSkewPolicy = policy
+	options.MaxClockSkewWait = 100 * time.Millisecond
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return generator
+}
+
+// 测试时钟回拨返回错误
+func TestSnowWorkerM2_ClockSkewError(t *testing.T) {
+	clock := newManualClock()
+	generator := newM2Generator(t, clock, ClockSkewError)
+
+	first, err := generator.NextIdErr()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	clock.Add(-5 * time.Millisecond)
+	if _, err = generator.NextIdErr(); !errors.Is(err, ErrClockMovedBackwards) {
+		t.Fatalf("expected ErrClockMovedBackwards, got %v", err)
+	}
+	if err = generator.FillErr(make([]int64, 3)); !errors.Is(err, ErrClockMovedBackwards) {
+		t.Fatalf("expected ErrClockMovedBackwards from FillErr, got %v", err)
+	}
+
+	clock.Add(5 * time.Millisecond)
+	if id, err := generator.NextIdErr(); err != nil || id <= first {
+		t.Errorf("expected id greater than %d after clock recovered, got %d, %v", first, id, err)
+	}
+}
+
+// 测试时钟回拨阻塞等待
+func TestSnowWorkerM2_ClockSkewWait(t *testing.T) {
+	clock := newManualClock()
+	generator := newM2Generator(t, clock, ClockSkewWait)
+
+	first, _ := generator.NextIdErr()
+	clock.Add(-50 * time.Millisecond)
+	sleptBefore := clock.slept
+	id, err := generator.NextIdErr()
+	if err != nil || id <= first {
+		t.Fatalf("expected id greater than %d, got %d, %v", first, id, err)
+	}
+	if slept := clock.slept - sleptBefore; slept != 50*time.Millisecond {
+		t.Errorf("expected to wait 50ms, waited %v", slept)
+	}
+
+	clock.Add(-time.Second)
+	if _, err = generator.NextIdErr(); !errors.Is(err, ErrClockMovedBackwards) {
+		t.Errorf("expected ErrClockMovedBackwards when skew exceeds max wait, got %v", err)
+	}
+}
+
+// 测试时钟回拨使用预留序列数
+func TestSnowWorkerM2_ClockSkewTurnBack(t *testing.T) {
+	clock := newManualClock()
+	generator := newM2Generator(t, clock, ClockSkewTurnBack)
+
+	seen := make(map[int64]struct{})
+	for i := 0; i < 10; i++ {
+		seen[generator.NextId()] = struct{}{}
+	}
+	clock.Add(-5 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		id, err := generator.NextIdErr()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		parts, _ := generator.Decompose(id)
+		if !parts.IsTurnBack {
+			t.Errorf("expected turn back id, got %+v", parts)
+		}
+		if _, ok := seen[id]; ok {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = struct{}{}
+	}
+
+	clock.Add(10 * time.Millisecond)
+	if parts, _ := generator.Decompose(generator.NextId()); parts.IsTurnBack {
+		t.Errorf("expected normal id after clock recovered, got %+v", parts)
+	}
+}
@@ -0,0 +1,157 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const m3SeqBits = 22 // 状态字低 22 位存放序列数，高位存放时间戳
+
+// snowWorkerM3 无锁实现，最后发出的时间戳和序列数打包在一个状态字中通过 CAS 更新。
+// 序列数用尽时向后借用时间戳（漂移），最多超前当前时间 TopOverCostCount 毫秒，超出后等待时钟追上；
+// TopOverCostCount 为 0 时即传统算法。时钟回拨时继续在最后的时间戳上发号，不会重复；
+// 回拨超出漂移上限时按 ClockSkewPolicy 处理。
+type snowWorkerM3 struct {
+	*snowWorkerM1
+	_State       atomic.Uint64
+	_TurningBack atomic.Bool // 正在使用回拨预留序列数发号，回拨状态由 snowWorkerM1 的锁保护
+}
+
+func newSnowWorkerM3(options *IdGeneratorOptions) iSnowWorker {
+	m3 := &snowWorkerM3{
+		snowWorkerM1: newSnowWorkerM1(options).(*snowWorkerM1),
+	}
+	m3._State.Store(packM3State(0, m3.MaxSeqNumber))
+	return m3
+}
+
+func packM3State(timeTick int64, seqNumber uint32) uint64 {
+	return uint64(timeTick)<<m3SeqBits | uint64(seqNumber)
+}
+
+func unpackM3State(state uint64) (int64, uint32) {
+	return int64(state >> m3SeqBits), uint32(state & (1<<m3SeqBits - 1))
+}
+
+func (m3 *snowWorkerM3) NextId() (int64, error) {
+	timeTick, seqNumber, _, err := m3.reserve(1)
+	if err != nil {
+		return 0, err
+	}
+	return m3.calcId(timeTick, seqNumber), nil
+}
+
+// NextIds 每次 CAS 预留当前毫秒内尽可能多的连续序列数，出错前已填充的 ID 仍然有效
+func (m3 *snowWorkerM3) NextIds(ids []int64) error {
+	for i := 0; i < len(ids); {
+		timeTick, seqNumber, count, err := m3.reserve(len(ids) - i)
+		if err != nil {
+			return err
+		}
+		for end := i + count; i < end; i++ {
+			ids[i] = m3.calcId(timeTick, seqNumber)
+			seqNumber++
+		}
+	}
+	return nil
+}
+
+// LastTimeTick 最后发出 ID 的时间戳（含漂移借用的未来时间）
+func (m3 *snowWorkerM3) LastTimeTick() int64 {
+	timeTick, _ := unpackM3State(m3._State.Load())
+	return timeTick
+}
+
+// reserve 预留最多 n 个连续序列数，返回时间戳、起始序列数和实际预留数量。
+// 时钟回拨超出漂移上限时按 ClockSkewPolicy 返回错误、等待或预留一个回拨序列数
+func (m3 *snowWorkerM3) reserve(n int) (int64, uint32, int, error) {
+	var deadline time.Time
+	for {
+		state := m3._State.Load()
+		lastTimeTick, lastSeqNumber := unpackM3State(state)
+		currentTimeTick := m3.GetCurrentTimeTick()
+
+		var timeTick int64
+		var seqNumber uint32
+		switch {
+		case currentTimeTick > lastTimeTick:
+			timeTick, seqNumber = currentTimeTick, m3.MinSeqNumber
+		case lastSeqNumber < m3.MaxSeqNumber:
+			timeTick, seqNumber = lastTimeTick, lastSeqNumber+1
+		case lastTimeTick-currentTimeTick < int64(m3.TopOverCostCount):
+			timeTick, seqNumber = lastTimeTick+1, m3.MinSeqNumber
+		default:
+			// 当前时间只会前进，借用的未来时间超出漂移上限的部分只能来自时钟回拨
+			skew := lastTimeTick - int64(m3.TopOverCostCount) - currentTimeTick
+			if skew <= 0 {
+				m3._Clock.Sleep(time.Duration(1) * time.Millisecond)
+				continue
+			}
+			switch m3.ClockSkewPolicy {
+			case ClockSkewError:
+				return 0, 0, 0, fmt.Errorf("%w: %d milliseconds", ErrClockMovedBackwards, skew)
+			case ClockSkewWait:
+				wait := time.Duration(skew) * time.Millisecond
+				if deadline.IsZero() {
+					deadline = m3._Clock.Now().Add(m3.MaxClockSkewWait)
+				}
+				if m3._Clock.Now().Add(wait).After(deadline) {
+					return 0, 0, 0, fmt.Errorf("%w: %d milliseconds exceeds max wait %v", ErrClockMovedBackwards, skew, m3.MaxClockSkewWait)
+				}
+				m3._Clock.Sleep(wait)
+				continue
+			default:
+				timeTick, seqNumber := m3.turnBack(lastTimeTick)
+				return timeTick, seqNumber, 1, nil
+			}
+		}
+
+		count := int(m3.MaxSeqNumber-seqNumber) + 1
+		if count > n {
+			count = n
+		}
+		if m3._State.CompareAndSwap(state, packM3State(timeTick, seqNumber+uint32(count)-1)) {
+			if m3._TurningBack.Load() {
+				m3.endTurnBack()
+			}
+			return timeTick, seqNumber, count, nil
+		}
+	}
+}
+
+// turnBack 与漂移算法相同，在最后时间戳之前的时间上使用回拨预留序列数（1-4）发号，
+// 正常发号的序列数不小于 MinSeqNumber，不会与之重复
+func (m3 *snowWorkerM3) turnBack(lastTimeTick int64) (int64, uint32) {
+	m3.Lock()
+	defer m3.Unlock()
+
+	if !m3._TurningBack.Load() || m3._TurnBackTimeTick < 1 {
+		m3._TurnBackTimeTick = lastTimeTick - 1
+		m3._TurnBackIndex++
+		if m3._TurnBackIndex > 4 {
+			m3._TurnBackIndex = 1
+		}
+		m3._TurningBack.Store(true)
+		m3.BeginTurnBackAction(m3._TurnBackTimeTick)
+	}
+	timeTick := m3._TurnBackTimeTick
+	m3._TurnBackTimeTick--
+	return timeTick, uint32(m3._TurnBackIndex)
+}
+
+// endTurnBack 时钟追上后结束回拨
+func (m3 *snowWorkerM3) endTurnBack() {
+	m3.Lock()
+	defer m3.Unlock()
+
+	if m3._TurningBack.Load() {
+		m3.EndTurnBackAction(m3._TurnBackTimeTick)
+		m3._TurnBackTimeTick = 0
+		m3._TurningBack.Store(false)
+	}
+}
+
+func (m3 *snowWorkerM3) calcId(timeTick int64, seqNumber uint32) int64 {
+	return timeTick<<m3._TimestampShift + m3._NodeBits + int64(seqNumber)
+}
@@ -0,0 +1,177 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newM3Generator(t testing.TB, seqBitLength byte, topOverCostCount uint32) *DefaultIdGenerator {
+	options := NewIdGeneratorOptions(9)
+	options.Method = 3
+	options.SeqBitLength = seqBitLength
+	options.TopOverCostCount = topOverCostCount
+	generator, err := NewDefaultIdGenerator(options)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return generator
+}
+
+// 测试数百个 goroutine 并发发号无重复，需配合 -race 运行
+func TestSnowWorkerM3_Concurrent(t *testing.T) {
+	for _, seqBitLength := range []byte{8, 12} {
+		t.Run("seq bits "+strconv.Itoa(int(seqBitLength)), func(t *testing.T) {
+			generator := newM3Generator(t, seqBitLength, 2000)
+
+			const goroutines, perGoroutine = 300, 200
+			results := make([][]int64, goroutines)
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					ids := make([]int64, 0, perGoroutine)
+					for i := 0; i < perGoroutine/2; i++ {
+						ids = append(ids, generator.NextId())
+					}
+					ids = append(ids, generator.NextIds(perGoroutine/2)...)
+					results[g] = ids
+				}(g)
+			}
+			wg.Wait()
+
+			seen := make(map[int64]struct{}, goroutines*perGoroutine)
+			for _, ids := range results {
+				for i, id := range ids {
+					if _, ok := seen[id]; ok {
+						t.Fatalf("duplicate id %d", id)
+					}
+					seen[id] = struct{}{}
+					if i > 0 && id <= ids[i-1] {
+						t.Fatalf("ids not increasing within goroutine: %d after %d", id, ids[i-1])
+					}
+					if parts, _ := generator.Decompose(id); parts.WorkerId != 9 || parts.SeqNumber < 5 {
+						t.Fatalf("unexpected id %d: %+v", id, parts)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 测试 TopOverCostCount 为 0 时不借用未来时间
+func TestSnowWorkerM3_Traditional(t *testing.T) {
+	generator := newM3Generator(t, 3, 0)
+	for i := 0; i < 50; i++ {
+		id := generator.NextId()
+		if parts, _ := generator.Decompose(id); parts.Time.After(time.Now()) {
+			t.Fatalf("id %d borrowed future time %v", id, parts.Time)
+		}
+	}
+}
+
+func BenchmarkNextId_Methods_Parallel(b *testing.B) {
+	for _, method := range []uint16{1, 2, 3} {
+		b.Run("method "+strconv.Itoa(int(method)), func(b *testing.B) {
+			options := NewIdGeneratorOptions(1)
+			options.Method = method
+			options.SeqBitLength = 16
+			generator, err := NewDefaultIdGenerator(options)
+			if err != nil {
+				b.Fatalf("unexpected error %v", err)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = generator.NextId()
+				}
+			})
+		})
+	}
+}
+
+// 测试时钟回拨超出漂移上限时按 ClockSkewPolicy 处理，而不是一直等待
+func TestSnowWorkerM3_ClockSkew(t *testing.T) {
+	newGenerator := func(policy ClockSkewPolicy) (*DefaultIdGenerator, *manualClock) {
+		clock := newManualClock()
+		options := NewIdGeneratorOptions(9)
+		options.Method = MethodLockFree
+		options.Clock = clock
+		options.TopOverCostCount = 10
+		options.ClockSkewPolicy = policy
+		options.MaxClockSkewWait = 100 * time.Millisecond
+		generator, err := NewDefaultIdGenerator(options)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return generator, clock
+	}
+	// drain 持续发号直到出错，回拨后先用完最后时间戳的序列数和漂移上限
+	drain := func(generator *DefaultIdGenerator) ([]int64, error) {
+		var ids []int64
+		for len(ids) < 5000 {
+			id, err := generator.NextIdErr()
+			if err != nil {
+				return ids, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	generator, clock := newGenerator(ClockSkewError)
+	generator.NextId()
+	clock.Add(-time.Second)
+	if _, err := drain(generator); !errors.Is(err, ErrClockMovedBackwards) {
+		t.Errorf("ClockSkewError: expected ErrClockMovedBackwards, got %v", err)
+	}
+	if err := generator.FillErr(make([]int64, 10)); !errors.Is(err, ErrClockMovedBackwards) {
+		t.Errorf("ClockSkewError FillErr: expected ErrClockMovedBackwards, got %v", err)
+	}
+
+	generator, clock = newGenerator(ClockSkewWait)
+	generator.NextId()
+	clock.Add(-time.Second)
+	ids, err := drain(generator)
+	if !errors.Is(err, ErrClockMovedBackwards) {
+		t.Fatalf("ClockSkewWait beyond max wait: expected ErrClockMovedBackwards, got %v", err)
+	}
+	last := ids[len(ids)-1]
+	clock.Add(time.Second - 50*time.Millisecond)
+	if id, err := generator.NextIdErr(); err != nil || id <= last {
+		t.Errorf("ClockSkewWait within max wait: %d, %v", id, err)
+	}
+	if limit := generator.ExtractTime(last).Add(-10 * time.Millisecond); clock.Now().Before(limit) {
+		t.Errorf("ClockSkewWait returned at %v before the clock caught up to %v", clock.Now(), limit)
+	}
+
+	var actions []OverCostActionArg
+	generator, clock = newGenerator(ClockSkewTurnBack)
+	generator.SnowWorker.(*snowWorkerM3)._GenIdActionHandler = func(arg OverCostActionArg) {
+		actions = append(actions, arg)
+	}
+	ids = generator.NextIds(100)
+	clock.Add(-time.Second)
+	skewed, err := drain(generator)
+	if err != nil {
+		t.Fatalf("ClockSkewTurnBack: unexpected error %v", err)
+	}
+	clock.Add(2 * time.Second)
+	ids = append(append(ids, skewed...), generator.NextId())
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = struct{}{}
+	}
+	if parts, _ := generator.Decompose(skewed[len(skewed)-1]); !parts.IsTurnBack {
+		t.Errorf("expected turn back id, got %+v", parts)
+	}
+	if len(actions) != 2 || actions[0].ActionType != ActionBeginTurnBack || actions[1].ActionType != ActionEndTurnBack {
+		t.Errorf("unexpected turn back actions %+v", actions)
+	}
+}
@@ -0,0 +1,195 @@
+package idgen
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// typeIdSuffixSize 后缀为 128 位的 Crockford base32 小写编码
+const typeIdSuffixSize = 26
+
+// Prefix 类型 ID 的实体类型，Prefix 返回该类型 ID 的前缀。
+// 前缀只能包含小写字母和下划线，不能以下划线开头或结尾，最长 63 个字符，可以为空
+type Prefix interface {
+	Prefix() string
+}
+
+// ID TypeID 风格的类型 ID，形如 user_01h455vb4pex5vsknk084sn02q，
+// 不同实体类型的 ID[User] 与 ID[Order] 是不同的 Go 类型，不能互相赋值。
+// 零值的后缀全为 0，文本形式实现了 JSON、encoding.TextMarshaler 以及 sql.Scanner / driver.Valuer
+type ID[T Prefix] struct {
+	value [16]byte
+}
+
+// NewID 以 UUIDv7 为后缀生成类型 ID
+func NewID[T Prefix]() (ID[T], error) {
+	if err := validateTypeIdPrefix(prefixOf[T]()); err != nil {
+		return ID[T]{}, err
+	}
+	u, err := uuid.NewV7()
+	if err != nil {
+		return ID[T]{}, err
+	}
+	return ID[T]{value: u}, nil
+}
+
+// MustNewID 同 NewID，出错时 panic
+func MustNewID[T Prefix]() ID[T] {
+	id, err := NewID[T]()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// IDFromBytes 以任意 128 位值为后缀构造类型 ID，如 ULIDGenerator.NextBytes 的结果或已有的 UUID
+func IDFromBytes[T Prefix](value [16]byte) ID[T] {
+	return ID[T]{value: value}
+}
+
+// ParseID 解析类型 ID，前缀必须与 T 一致，不合法时返回 *ParseError
+func ParseID[T Prefix](s string) (ID[T], error) {
+	prefix, value, err := SplitTypeID(s)
+	if err != nil {
+		return ID[T]{}, err
+	}
+	if want := prefixOf[T](); prefix != want {
+		return ID[T]{}, malformedf("typeid", s, "prefix %q, want %q", prefix, want)
+	}
+	return ID[T]{value: value}, nil
+}
+
+// SplitTypeID 解析任意前缀的类型 ID，返回前缀和 128 位后缀
+func SplitTypeID(s string) (prefix string, value [16]byte, err error) {
+	suffix := s
+	if i := strings.LastIndexByte(s, '_'); i >= 0 {
+		prefix, suffix = s[:i], s[i+1:]
+		if prefix == "" {
+			return "", value, malformedf("typeid", s, "empty prefix before separator")
+		}
+	}
+	if err = validateTypeIdPrefix(prefix); err != nil {
+		return "", value, malformed("typeid", s, err)
+	}
+	if len(suffix) != typeIdSuffixSize {
+		return "", value, malformedf("typeid", s, "suffix length %d, want %d", len(suffix), typeIdSuffixSize)
+	}
+	if suffix != strings.ToLower(suffix) {
+		return "", value, malformedf("typeid", s, "suffix must be lowercase")
+	}
+	u, err := ulid.ParseStrict(suffix)
+	if err != nil {
+		return "", value, malformed("typeid", s, err)
+	}
+	return prefix, u, nil
+}
+
+// validateTypeIdPrefix 按 TypeID 规范校验前缀
+func validateTypeIdPrefix(prefix string) error {
+	if len(prefix) > 63 {
+		return fmt.Errorf("prefix %q is longer than 63 characters", prefix)
+	}
+	if strings.HasPrefix(prefix, "_") || strings.HasSuffix(prefix, "_") {
+		return fmt.Errorf("prefix %q must not start or end with an underscore", prefix)
+	}
+	for i := 0; i < len(prefix); i++ {
+		if c := prefix[i]; c != '_' && (c < 'a' || c > 'z') {
+			return fmt.Errorf("prefix %q contains invalid character %q", prefix, c)
+		}
+	}
+	return nil
+}
+
+func prefixOf[T Prefix]() string {
+	var entity T
+	return entity.Prefix()
+}
+
+// Prefix 前缀
+func (id ID[T]) Prefix() string {
+	return prefixOf[T]()
+}
+
+// Suffix 后缀，26 位小写 Crockford base32
+func (id ID[T]) Suffix() string {
+	return strings.ToLower(ulid.ULID(id.value).String())
+}
+
+// String 完整的文本形式，前缀为空时只有后缀
+func (id ID[T]) String() string {
+	if prefix := id.Prefix(); prefix != "" {
+		return prefix + "_" + id.Suffix()
+	}
+	return id.Suffix()
+}
+
+// Bytes 后缀的 128 位值
+func (id ID[T]) Bytes() [16]byte {
+	return id.value
+}
+
+// UUID 后缀对应的 UUID 字符串
+func (id ID[T]) UUID() string {
+	return uuid.UUID(id.value).String()
+}
+
+// Time 后缀前 48 位表示的毫秒时间，对 UUIDv7 和 ULID 后缀有效
+func (id ID[T]) Time() time.Time {
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(id.value[:8]) >> 16))
+}
+
+// IsZero 是否为零值
+func (id ID[T]) IsZero() bool {
+	return id.value == [16]byte{}
+}
+
+func (id ID[T]) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *ID[T]) UnmarshalText(text []byte) error {
+	parsed, err := ParseID[T](string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Scan 实现 sql.Scanner，接受文本形式，以及 UUID 列返回的 UUID 字符串或 16 字节二进制
+func (id *ID[T]) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*id = ID[T]{}
+		return nil
+	case string:
+		return id.scanString(v)
+	case []byte:
+		if len(v) == 16 {
+			copy(id.value[:], v)
+			return nil
+		}
+		return id.scanString(string(v))
+	default:
+		return fmt.Errorf("idgen: cannot scan %T into typed id", src)
+	}
+}
+
+func (id *ID[T]) scanString(s string) error {
+	if u, err := uuid.Parse(s); err == nil {
+		id.value = u
+		return nil
+	}
+	return id.UnmarshalText([]byte(s))
+}
+
+// Value 实现 driver.Valuer，以文本形式存储
+func (id ID[T]) Value() (driver.Value, error) {
+	return id.String(), nil
+}
@@ -0,0 +1,139 @@
+package idgen
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testUser struct{}
+
+func (testUser) Prefix() string { return "user" }
+
+type testOrder struct{}
+
+func (testOrder) Prefix() string { return "order_item" }
+
+type testBare struct{}
+
+func (testBare) Prefix() string { return "" }
+
+type testInvalid struct{}
+
+func (testInvalid) Prefix() string { return "User" }
+
+var (
+	_ sql.Scanner   = (*ID[testUser])(nil)
+	_ driver.Valuer = ID[testUser]{}
+)
+
+func TestID_SpecExample(t *testing.T) {
+	// TypeID 规范中的示例
+	id, err := ParseID[testUser]("user_01h455vb4pex5vsknk084sn02q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.UUID() != "01890a5d-ac96-774b-bcce-b302099a8057" {
+		t.Errorf("UUID() = %s", id.UUID())
+	}
+	if id.Time().UnixMilli() != 0x01890a5dac96 {
+		t.Errorf("Time() = %v", id.Time())
+	}
+
+	var bare ID[testBare]
+	if bare.String() != "00000000000000000000000000" || !bare.IsZero() {
+		t.Errorf("zero bare id = %q", bare.String())
+	}
+}
+
+func TestNewID(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	user := MustNewID[testUser]()
+	order, err := NewID[testOrder]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(user.String(), "user_") || !strings.HasPrefix(order.String(), "order_item_") {
+		t.Errorf("unexpected ids %s, %s", user, order)
+	}
+	if user.Time().Before(before) || user.Time().After(time.Now()) {
+		t.Errorf("Time() = %v", user.Time())
+	}
+
+	parsed, err := ParseID[testOrder](order.String())
+	if err != nil || parsed != order {
+		t.Errorf("ParseID(%s) = %s, %v", order, parsed, err)
+	}
+	if prefix, _, err := SplitTypeID(order.String()); err != nil || prefix != "order_item" {
+		t.Errorf("SplitTypeID = %q, %v", prefix, err)
+	}
+
+	if _, err := NewID[testInvalid](); err == nil {
+		t.Errorf("expected error for invalid prefix")
+	}
+
+	b, _ := NewULIDGenerator().NextBytes()
+	if fromULID := IDFromBytes[testUser](b); fromULID.Bytes() != b {
+		t.Errorf("IDFromBytes lost bits")
+	}
+}
+
+func TestParseID_Invalid(t *testing.T) {
+	invalid := map[string]string{
+		"order_01h455vb4pex5vsknk084sn02q": "prefix",
+		"user_01H455VB4PEX5VSKNK084SN02Q":  "lowercase",
+		"user_81h455vb4pex5vsknk084sn02q":  "overflow",
+		"user_01h455vb4pex5vsknk084sn02":   "length",
+		"_01h455vb4pex5vsknk084sn02q":      "empty prefix",
+		"User_01h455vb4pex5vsknk084sn02q":  "invalid character",
+		"user__01h455vb4pex5vsknk084sn02q": "underscore",
+		"user_01h455vb4pex5vsknk084sn0uq":  "bad data characters",
+	}
+	for s, reason := range invalid {
+		_, err := ParseID[testUser](s)
+		var parseErr *ParseError
+		if !errors.Is(err, ErrMalformedId) || !errors.As(err, &parseErr) || !strings.Contains(parseErr.Reason, reason) {
+			t.Errorf("ParseID(%q) = %v; want reason containing %q", s, err, reason)
+		}
+	}
+}
+
+func TestID_Encoding(t *testing.T) {
+	type order struct {
+		Id    ID[testOrder] `json:"id"`
+		Buyer ID[testUser]  `json:"buyer"`
+	}
+	in := order{Id: MustNewID[testOrder](), Buyer: MustNewID[testUser]()}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out order
+	if err = json.Unmarshal(data, &out); err != nil || out != in {
+		t.Errorf("json round trip %s -> %+v, %v", data, out, err)
+	}
+
+	// 前缀不匹配的 JSON 被拒绝
+	swapped := strings.Replace(string(data), `"buyer":"user_`, `"buyer":"order_item_`, 1)
+	if err = json.Unmarshal([]byte(swapped), &out); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("expected prefix mismatch error, got %v", err)
+	}
+
+	value, _ := in.Buyer.Value()
+	var scanned ID[testUser]
+	for _, src := range []any{value, []byte(value.(string)), in.Buyer.UUID(), in.Buyer.value[:]} {
+		if err = scanned.Scan(src); err != nil || scanned != in.Buyer {
+			t.Errorf("Scan(%v) = %s, %v", src, scanned, err)
+		}
+	}
+	if err = scanned.Scan(nil); err != nil || !scanned.IsZero() {
+		t.Errorf("Scan(nil) = %s, %v", scanned, err)
+	}
+	if err = scanned.Scan(42); err == nil {
+		t.Errorf("expected error scanning int")
+	}
+}
@@ -1,14 +1,144 @@
 package idgen
 
 import (
-	"github.com/oklog/ulid/v2"
-	"math/rand"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
 	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+	"github.com/oklog/ulid/v2"
 )
 
-func GenULId() string {
-	entropy := ulid.Monotonic(rand.New(rand.NewSource(time.Now().UnixNano())), 0)
-	id := ulid.MustNew(ulid.Timestamp(time.Now()), entropy)
+// defaultULIdGenerator GenULId 使用的进程级生成器
+var defaultULIdGenerator = NewULIDGenerator()
 
+// GenULId 使用进程级 ULIDGenerator 生成 ULID，同一进程内严格递增
+func GenULId() string {
+	id, err := defaultULIdGenerator.NextULID()
+	if err != nil {
+		panic(err)
+	}
 	return id.String()
 }
+
+// ParsedULId 解析后的 ULID
+type ParsedULId struct {
+	ULID      ulid.ULID
+	Timestamp time.Time // 毫秒精度的内嵌时间
+	Entropy   []byte    // 80 位随机部分
+}
+
+// ParseULId 解析并校验 ULID，只接受 26 位 Crockford base32，不合法时返回 *ParseError
+func ParseULId(id string) (ParsedULId, error) {
+	if len(id) != ulid.EncodedSize {
+		return ParsedULId{}, malformedf("ulid", id, "length %d, want %d", len(id), ulid.EncodedSize)
+	}
+	u, err := ulid.ParseStrict(id)
+	if err != nil {
+		return ParsedULId{}, malformed("ulid", id, err)
+	}
+	return ParsedULId{ULID: u, Timestamp: ulid.Time(u.Time()), Entropy: u.Entropy()}, nil
+}
+
+// ULIDOption ULIDGenerator 的配置项
+type ULIDOption func(*ULIDGenerator)
+
+// WithULIDEntropy 替换随机源，默认使用 crypto/rand
+func WithULIDEntropy(entropy io.Reader) ULIDOption {
+	return func(g *ULIDGenerator) {
+		g.entropy = entropy
+	}
+}
+
+// WithULIDClock 替换时钟，测试中可注入 timeutil.FakeClock
+func WithULIDClock(clock timeutil.Clock) ULIDOption {
+	return func(g *ULIDGenerator) {
+		g.clock = clock
+	}
+}
+
+// ULIDGenerator 并发安全的单调 ULID 生成器。
+// 同一毫秒内在上一个 ULID 的随机部分上随机递增；时钟回拨或随机部分溢出时沿用或借用下一毫秒，
+// 因此同一生成器发出的 ULID 在所有 goroutine 之间严格递增
+type ULIDGenerator struct {
+	mu        sync.Mutex
+	entropy   io.Reader
+	clock     timeutil.Clock
+	monotonic *ulid.MonotonicEntropy
+	last      ulid.ULID
+}
+
+// NewULIDGenerator 创建 ULIDGenerator
+func NewULIDGenerator(opts ...ULIDOption) *ULIDGenerator {
+	g := &ULIDGenerator{entropy: rand.Reader, clock: timeutil.RealClock{}}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.monotonic = ulid.Monotonic(g.entropy, 0)
+	return g
+}
+
+// NextULID 生成下一个 ULID
+func (g *ULIDGenerator) NextULID() (ulid.ULID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := ulid.Timestamp(g.clock.Now())
+	if lastMs := g.last.Time(); ms < lastMs {
+		ms = lastMs
+	}
+	for {
+		id, err := ulid.New(ms, g.monotonic)
+		if errors.Is(err, ulid.ErrMonotonicOverflow) {
+			ms++
+			continue
+		}
+		if err != nil {
+			return ulid.ULID{}, err
+		}
+		g.last = id
+		return id, nil
+	}
+}
+
+// NextBytes 生成下一个 ULID 的 16 字节二进制形式，字节序与字符串形式的排序一致
+func (g *ULIDGenerator) NextBytes() ([16]byte, error) {
+	id, err := g.NextULID()
+	return id, err
+}
+
+func (*ULIDGenerator) Scheme() string {
+	return "ulid"
+}
+
+func (g *ULIDGenerator) Next() (string, error) {
+	id, err := g.NextULID()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+func (g *ULIDGenerator) NextN(n int) ([]string, error) {
+	return nextN(n, g.Next)
+}
+
+func (g *ULIDGenerator) Parse(id string) (ParsedId, error) {
+	parsed, err := ParseULId(id)
+	if err != nil {
+		return ParsedId{}, err
+	}
+	return ParsedId{Scheme: g.Scheme(), Value: parsed.ULID.String(), Bytes: parsed.ULID[:], Timestamp: parsed.Timestamp}, nil
+}
+
+func (g *ULIDGenerator) Validate(id string) error {
+	_, err := g.Parse(id)
+	return err
+}
+
+func (g *ULIDGenerator) Timestamp(id string) (time.Time, error) {
+	parsed, err := g.Parse(id)
+	return parsed.Timestamp, err
+}
@@ -0,0 +1,95 @@
+package idgen
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestULIDGenerator_Concurrent(t *testing.T) {
+	g := NewULIDGenerator()
+
+	const goroutines, perGoroutine = 8, 1000
+	results := make([][]ulid.ULID, goroutines)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				id, err := g.NextULID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[i] = append(results[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var all []ulid.ULID
+	for _, ids := range results {
+		for j := 1; j < len(ids); j++ {
+			if ids[j].Compare(ids[j-1]) <= 0 {
+				t.Fatalf("ulids within a goroutine are not increasing: %s <= %s", ids[j], ids[j-1])
+			}
+		}
+		all = append(all, ids...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Compare(all[j]) < 0 })
+	for i := 1; i < len(all); i++ {
+		if all[i] == all[i-1] {
+			t.Fatalf("duplicate ulid %s", all[i])
+		}
+	}
+}
+
+func TestULIDGenerator_ClockBackwards(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.UnixMilli(1700000000000))
+	g := NewULIDGenerator(WithULIDClock(clock))
+
+	first, _ := g.NextULID()
+	clock.Set(clock.Now().Add(-time.Second))
+	second, _ := g.NextULID()
+	if second.Compare(first) <= 0 || second.Time() != first.Time() {
+		t.Errorf("ulid after clock moved backwards = %s, want > %s in the same millisecond", second, first)
+	}
+}
+
+func TestULIDGenerator_Overflow(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.UnixMilli(1700000000000))
+	g := NewULIDGenerator(WithULIDClock(clock), WithULIDEntropy(&maxEntropy{}))
+
+	first, err := g.NextBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := g.NextBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(second[:], first[:]) <= 0 || ulid.ULID(second).Time() != ulid.ULID(first).Time()+1 {
+		t.Errorf("overflowed ulid %x should move to the next millisecond after %x", second, first)
+	}
+}
+
+// maxEntropy 首个随机部分全为 1，之后的递增必然溢出
+type maxEntropy struct {
+	read int
+}
+
+func (e *maxEntropy) Read(p []byte) (int, error) {
+	for i := range p {
+		if p[i] = 0x01; e.read < 10 {
+			p[i] = 0xff
+		}
+		e.read++
+	}
+	return len(p), nil
+}
@@ -1,13 +1,35 @@
 package idgen
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrUnsupportedVersion 不支持的 UUID 版本
+	ErrUnsupportedVersion = errors.New("idgen: unsupported uuid version")
+	// ErrMissingName v3 / v5 未提供 name
+	ErrMissingName = errors.New("idgen: uuid name is required")
+)
+
+// RFC 4122 预定义的命名空间，用于 v3 / v5
+const (
+	NamespaceDNS  = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	NamespaceURL  = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+	NamespaceOID  = "6ba7b812-9dad-11d1-80b4-00c04fd430c8"
+	NamespaceX500 = "6ba7b814-9dad-11d1-80b4-00c04fd430c8"
+)
+
 // GenUUId 根据版本生成 UUID。
-// ver: 支持 "v1", "v3", "v4", "v5"（不支持 v2）
-// name 和 namespace 仅在 v3 / v5 时需要。
+// ver: 支持 "v1", "v3", "v4", "v5", "v6", "v7"（不支持 v2，v8 使用 GenUUIdV8）
+// name 仅在 v3 / v5 时需要，命名空间固定为 NamespaceDNS，其他命名空间使用 GenNameUUId。
+// v7 在进程内单调递增：同一毫秒内使用 12 位亚毫秒计数保证顺序，适合作为数据库主键。
 func GenUUId(ver string, name string) (string, error) {
 	switch ver {
 	case "v1":
@@ -17,22 +39,215 @@ func GenUUId(ver string, name string) (string, error) {
 		}
 		return id.String(), nil
 
-	case "v3":
-		if name == "" {
-			return "", errors.New("v3版本需要提供name参数")
-		}
-		return uuid.NewMD5(uuid.NameSpaceDNS, []byte(name)).String(), nil
+	case "v3", "v5":
+		return GenNameUUId(ver, NamespaceDNS, name)
 
 	case "v4":
 		return uuid.New().String(), nil
 
-	case "v5":
-		if name == "" {
-			return "", errors.New("v5版本需要提供name参数")
+	case "v6":
+		id, err := newUUIdV6()
+		if err != nil {
+			return "", err
+		}
+		return id.String(), nil
+
+	case "v7":
+		id, err := uuid.NewV7()
+		if err != nil {
+			return "", err
 		}
-		return uuid.NewSHA1(uuid.NameSpaceDNS, []byte(name)).String(), nil
+		return id.String(), nil
 
 	default:
-		return "", errors.New("不支持的UUID版本: " + ver)
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedVersion, ver)
+	}
+}
+
+// GenNameUUId 在指定命名空间下生成 v3 / v5 UUID，相同的命名空间和 name 总是得到相同的 UUID。
+// namespace 可以是 Namespace* 常量或任意 UUID 字符串
+func GenNameUUId(ver string, namespace string, name string) (string, error) {
+	if ver != "v3" && ver != "v5" {
+		return "", fmt.Errorf("%w: %q is not name-based", ErrUnsupportedVersion, ver)
+	}
+	if name == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingName, ver)
+	}
+	ns, err := uuid.Parse(namespace)
+	if err != nil {
+		return "", malformed("uuid", namespace, err)
+	}
+	if ver == "v3" {
+		return uuid.NewMD5(ns, []byte(name)).String(), nil
+	}
+	return uuid.NewSHA1(ns, []byte(name)).String(), nil
+}
+
+// GenKeyedUUId 由多段结构化输入（如租户 + 实体主键）生成确定性的 v5 UUID。
+// 每段按长度前缀编码后拼接，("ab", "c") 与 ("a", "bc") 得到不同的结果
+func GenKeyedUUId(namespace string, parts ...string) (string, error) {
+	if len(parts) == 0 {
+		return "", fmt.Errorf("%w: no key parts", ErrMissingName)
+	}
+	var name []byte
+	for _, part := range parts {
+		name = binary.AppendUvarint(name, uint64(len(part)))
+		name = append(name, part...)
+	}
+	return GenNameUUId("v5", namespace, string(name))
+}
+
+// newUUIdV6 按 RFC 9562 将 v1 的时间戳重排为高位在前。
+// uuid.NewV6 直接写入 64 位时间后覆盖版本位，会丢失时间中的 4 位，因此这里自行转换
+func newUUIdV6() (uuid.UUID, error) {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return id, err
+	}
+	timestamp := uint64(id.Time())
+	binary.BigEndian.PutUint32(id[0:4], uint32(timestamp>>28))
+	binary.BigEndian.PutUint16(id[4:6], uint16(timestamp>>12))
+	binary.BigEndian.PutUint16(id[6:8], 0x6000|uint16(timestamp&0x0fff))
+	return id, nil
+}
+
+// GenUUIdV8 使用调用方自定义的 128 位布局生成 UUIDv8，版本位和变体位会被覆盖，其余 122 位原样保留
+func GenUUIdV8(custom [16]byte) string {
+	return newUUIdV8(custom).String()
+}
+
+func newUUIdV8(custom [16]byte) uuid.UUID {
+	id := uuid.UUID(custom)
+	id[6] = id[6]&0x0f | 0x80 // version 8
+	id[8] = id[8]&0x3f | 0x80 // variant RFC 9562
+	return id
+}
+
+// UUIdTimestamp 提取 v1、v6、v7 UUID 内嵌的时间，其他版本返回 ErrNoTimestamp
+func UUIdTimestamp(id string) (time.Time, error) {
+	parsed, err := ParseUUId(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return uuidTime(parsed.UUID)
+}
+
+func uuidTime(u uuid.UUID) (time.Time, error) {
+	switch u.Version() {
+	case 1:
+		return time.Unix(u.Time().UnixTime()), nil
+	case 6:
+		// time_high(32) | time_mid(16) | ver(4) time_low(12)，单位 100ns，起点 1582-10-15
+		timestamp := uint64(binary.BigEndian.Uint32(u[0:4]))<<28 |
+			uint64(binary.BigEndian.Uint16(u[4:6]))<<12 |
+			uint64(binary.BigEndian.Uint16(u[6:8])&0x0fff)
+		return time.Unix(uuid.Time(timestamp).UnixTime()), nil
+	case 7:
+		// 前 48 位为 Unix 毫秒时间戳
+		millis := int64(binary.BigEndian.Uint64(u[0:8]) >> 16)
+		return time.UnixMilli(millis), nil
+	default:
+		return time.Time{}, ErrNoTimestamp
+	}
+}
+
+// ParsedUUId 解析后的 UUID
+type ParsedUUId struct {
+	UUID      uuid.UUID
+	Version   int       // 版本号 1-8
+	Timestamp time.Time // v1 / v6 / v7 的内嵌时间，其他版本为零值
+}
+
+// ParseUUId 解析并校验 UUID，接受标准格式、无连字符格式、urn:uuid: 前缀和花括号形式。
+// 变体必须为 RFC 9562，versions 非空时版本必须在其中，不合法时返回 *ParseError
+func ParseUUId(id string, versions ...int) (ParsedUUId, error) {
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return ParsedUUId{}, malformed("uuid", id, err)
+	}
+	if u.Variant() != uuid.RFC4122 {
+		return ParsedUUId{}, malformedf("uuid", id, "variant %s, want RFC4122", u.Variant())
+	}
+	parsed := ParsedUUId{UUID: u, Version: int(u.Version())}
+	if parsed.Version < 1 || parsed.Version > 8 {
+		return ParsedUUId{}, malformedf("uuid", id, "unknown version %d", parsed.Version)
+	}
+	if len(versions) > 0 && !slices.Contains(versions, parsed.Version) {
+		return ParsedUUId{}, malformedf("uuid", id, "version %d, want %v", parsed.Version, versions)
+	}
+	parsed.Timestamp, _ = uuidTime(u)
+	return parsed, nil
+}
+
+// uuIdGenerator UUID 方案，仅支持不需要 name 的版本
+type uuIdGenerator struct {
+	version string
+	layout  func() ([16]byte, error) // v8 自定义布局
+}
+
+// NewUUIdGenerator 创建指定版本的 UUID 方案，ver 支持 "v1", "v4", "v6", "v7"
+func NewUUIdGenerator(ver string) (IdGenerator, error) {
+	switch ver {
+	case "v1", "v4", "v6", "v7":
+		return uuIdGenerator{version: ver}, nil
+	default:
+		return nil, fmt.Errorf("%w: uuid %s: %w", ErrUnknownScheme, ver, ErrUnsupportedVersion)
+	}
+}
+
+// NewUUIdV8Generator 创建 UUIDv8 方案，layout 每次返回调用方自定义布局的 128 位数据
+func NewUUIdV8Generator(layout func() ([16]byte, error)) IdGenerator {
+	return uuIdGenerator{version: "v8", layout: layout}
+}
+
+func (g uuIdGenerator) Scheme() string {
+	return "uuid" + g.version
+}
+
+func (g uuIdGenerator) Next() (string, error) {
+	if g.layout != nil {
+		custom, err := g.layout()
+		if err != nil {
+			return "", err
+		}
+		return GenUUIdV8(custom), nil
+	}
+	return GenUUId(g.version, "")
+}
+
+func (g uuIdGenerator) NextN(n int) ([]string, error) {
+	return nextN(n, g.Next)
+}
+
+func (g uuIdGenerator) parse(id string) (ParsedUUId, error) {
+	version, _ := strconv.Atoi(g.version[1:])
+	parsed, err := ParseUUId(id, version)
+	if err != nil {
+		err.(*ParseError).Scheme = g.Scheme()
+	}
+	return parsed, err
+}
+
+func (g uuIdGenerator) Parse(id string) (ParsedId, error) {
+	parsed, err := g.parse(id)
+	if err != nil {
+		return ParsedId{}, err
+	}
+	return ParsedId{Scheme: g.Scheme(), Value: parsed.UUID.String(), Bytes: parsed.UUID[:], Timestamp: parsed.Timestamp}, nil
+}
+
+func (g uuIdGenerator) Validate(id string) error {
+	_, err := g.parse(id)
+	return err
+}
+
+func (g uuIdGenerator) Timestamp(id string) (time.Time, error) {
+	parsed, err := g.parse(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if parsed.Timestamp.IsZero() {
+		return time.Time{}, ErrNoTimestamp
 	}
+	return parsed.Timestamp, nil
 }
@@ -0,0 +1,136 @@
+package idgen
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGenUUId_TimeOrdered(t *testing.T) {
+	for _, ver := range []string{"v6", "v7"} {
+		t.Run(ver, func(t *testing.T) {
+			before := time.Now().Truncate(time.Millisecond)
+			ids := make([]string, 1000)
+			for i := range ids {
+				id, err := GenUUId(ver, "")
+				if err != nil {
+					t.Fatalf("GenUUId(%s): %v", ver, err)
+				}
+				ids[i] = id
+			}
+			after := time.Now()
+
+			// v6 同一时间刻度内依靠时钟序列区分，只保证时间部分有序
+			timeParts := make([]string, len(ids))
+			for i, id := range ids {
+				timeParts[i] = id[:18]
+			}
+			if ver == "v7" && !sort.StringsAreSorted(ids) || !sort.StringsAreSorted(timeParts) {
+				t.Errorf("%s ids are not lexicographically ordered", ver)
+			}
+			for _, id := range ids {
+				u := uuid.MustParse(id)
+				if "v"+string(rune('0'+u.Version())) != ver || u.Variant() != uuid.RFC4122 {
+					t.Fatalf("unexpected version/variant for %s", id)
+				}
+				ts, err := UUIdTimestamp(id)
+				if err != nil || ts.Before(before) || ts.After(after) {
+					t.Fatalf("UUIdTimestamp(%s) = %v, %v; want between %v and %v", id, ts, err, before, after)
+				}
+			}
+		})
+	}
+}
+
+func TestUUIdTimestamp(t *testing.T) {
+	v1, _ := GenUUId("v1", "")
+	if ts, err := UUIdTimestamp(v1); err != nil || time.Since(ts) > time.Second {
+		t.Errorf("UUIdTimestamp(v1) = %v, %v", ts, err)
+	}
+
+	v4, _ := GenUUId("v4", "")
+	if _, err := UUIdTimestamp(v4); !errors.Is(err, ErrNoTimestamp) {
+		t.Errorf("expected ErrNoTimestamp for v4, got %v", err)
+	}
+	if _, err := UUIdTimestamp("garbage"); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("expected ErrMalformedId, got %v", err)
+	}
+}
+
+func TestGenUUIdV8(t *testing.T) {
+	var custom [16]byte
+	for i := range custom {
+		custom[i] = 0xff
+	}
+	u := uuid.MustParse(GenUUIdV8(custom))
+	if u.Version() != 8 || u.Variant() != uuid.RFC4122 {
+		t.Fatalf("unexpected version %d variant %v", u.Version(), u.Variant())
+	}
+	if u[0] != 0xff || u[15] != 0xff || u[6]&0x0f != 0x0f || u[8]&0x3f != 0x3f {
+		t.Errorf("custom bits were not preserved: %x", u[:])
+	}
+
+	var counter byte
+	generator := NewUUIdV8Generator(func() ([16]byte, error) {
+		counter++
+		return [16]byte{15: counter}, nil
+	})
+	id, err := generator.Next()
+	if err != nil || generator.Validate(id) != nil {
+		t.Fatalf("Next() = %s, %v", id, err)
+	}
+	if _, err = generator.Timestamp(id); !errors.Is(err, ErrNoTimestamp) {
+		t.Errorf("expected ErrNoTimestamp for v8, got %v", err)
+	}
+}
+
+func TestGenNameUUId(t *testing.T) {
+	// RFC 9562 附录中的 v5 示例：NamespaceDNS + "www.example.com"
+	if id, err := GenUUId("v5", "www.example.com"); err != nil || id != "2ed6657d-e927-568b-95e1-2665a8aea6a2" {
+		t.Errorf("GenUUId(v5) = %s, %v", id, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, ns := range []string{NamespaceDNS, NamespaceURL, NamespaceOID, NamespaceX500, "0191e8a4-4c2b-7000-8000-000000000000"} {
+		id, err := GenNameUUId("v3", ns, "lotus")
+		if err != nil {
+			t.Fatalf("GenNameUUId(%s): %v", ns, err)
+		}
+		if uuid.MustParse(id).Version() != 3 || seen[id] {
+			t.Errorf("unexpected id %s for namespace %s", id, ns)
+		}
+		seen[id] = true
+	}
+
+	if _, err := GenNameUUId("v5", "not-a-uuid", "lotus"); !errors.Is(err, ErrMalformedId) {
+		t.Errorf("expected ErrMalformedId, got %v", err)
+	}
+	if _, err := GenNameUUId("v4", NamespaceDNS, "lotus"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if _, err := GenUUId("v3", ""); !errors.Is(err, ErrMissingName) {
+		t.Errorf("expected ErrMissingName, got %v", err)
+	}
+	if _, err := GenUUId("v2", ""); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestGenKeyedUUId(t *testing.T) {
+	a, _ := GenKeyedUUId(NamespaceURL, "tenant-1", "order-42")
+	b, _ := GenKeyedUUId(NamespaceURL, "tenant-1", "order-42")
+	if a != b {
+		t.Errorf("keyed uuid is not deterministic: %s != %s", a, b)
+	}
+	c, _ := GenKeyedUUId(NamespaceURL, "tenant-1order", "-42")
+	d, _ := GenKeyedUUId(NamespaceURL, "tenant-2", "order-42")
+	if a == c || a == d {
+		t.Errorf("distinct keys produced the same uuid")
+	}
+	if _, err := GenKeyedUUId(NamespaceURL); !errors.Is(err, ErrMissingName) {
+		t.Errorf("expected ErrMissingName, got %v", err)
+	}
+}
@@ -1,8 +1,73 @@
 package idgen
 
-import "github.com/rs/xid"
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
 
 func GenXId() string {
 	id := xid.New()
 	return id.String()
 }
+
+// xidEncodedSize XID 字符串长度
+const xidEncodedSize = 20
+
+// ParsedXId 解析后的 XID
+type ParsedXId struct {
+	XID       xid.ID
+	Timestamp time.Time // 秒精度的内嵌时间
+	Machine   []byte    // 3 字节机器标识
+	Pid       uint16    // 进程 ID
+	Counter   int32     // 24 位计数器
+}
+
+// ParseXId 解析并校验 XID，只接受 20 位小写 base32hex，不合法时返回 *ParseError
+func ParseXId(id string) (ParsedXId, error) {
+	if len(id) != xidEncodedSize {
+		return ParsedXId{}, malformedf("xid", id, "length %d, want %d", len(id), xidEncodedSize)
+	}
+	if i := strings.IndexFunc(id, func(r rune) bool { return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'v') }); i >= 0 {
+		return ParsedXId{}, malformedf("xid", id, "invalid character %q at position %d", id[i], i)
+	}
+	x, err := xid.FromString(id)
+	if err != nil {
+		return ParsedXId{}, malformed("xid", id, err)
+	}
+	return ParsedXId{XID: x, Timestamp: x.Time(), Machine: x.Machine(), Pid: x.Pid(), Counter: x.Counter()}, nil
+}
+
+// xidGenerator XID 方案
+type xidGenerator struct{}
+
+func (xidGenerator) Scheme() string {
+	return "xid"
+}
+
+func (xidGenerator) Next() (string, error) {
+	return GenXId(), nil
+}
+
+func (g xidGenerator) NextN(n int) ([]string, error) {
+	return nextN(n, g.Next)
+}
+
+func (g xidGenerator) Parse(id string) (ParsedId, error) {
+	parsed, err := ParseXId(id)
+	if err != nil {
+		return ParsedId{}, err
+	}
+	return ParsedId{Scheme: g.Scheme(), Value: parsed.XID.String(), Bytes: parsed.XID.Bytes(), Timestamp: parsed.Timestamp}, nil
+}
+
+func (g xidGenerator) Validate(id string) error {
+	_, err := g.Parse(id)
+	return err
+}
+
+func (g xidGenerator) Timestamp(id string) (time.Time, error) {
+	parsed, err := g.Parse(id)
+	return parsed.Timestamp, err
+}
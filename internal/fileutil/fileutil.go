@@ -0,0 +1,42 @@
+// Package fileutil 文件存储共用的原子写入和进程间文件锁
+package fileutil
+
+import (
+	"os"
+)
+
+// WriteFileAtomic 先写临时文件并落盘再重命名，崩溃时不会留下写了一半的文件
+func WriteFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// WithLock 持有 path 的独占文件锁执行 fn，文件不存在时自动创建。
+// 文件锁只在进程间互斥，进程内的调用方需要自行加锁；不支持文件锁的平台返回 errors.ErrUnsupported
+func WithLock(path string, fn func() error) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = lockFile(file); err != nil {
+		return err
+	}
+	defer unlockFile(file)
+
+	return fn()
+}
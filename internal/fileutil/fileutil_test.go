@@ -0,0 +1,52 @@
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got, err := os.ReadFile(path); err != nil || string(got) != content {
+			t.Errorf("ReadFile = %q, %v; want %q", got, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+// 测试同一把文件锁下的读改写不会丢失更新
+func TestWithLock(t *testing.T) {
+	dir := t.TempDir()
+	lock, counter := filepath.Join(dir, ".lock"), filepath.Join(dir, "counter")
+	if err := WithLock(lock, func() error { return nil }); errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("file locks are not supported on this platform")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithLock(lock, func() error {
+				content, _ := os.ReadFile(counter)
+				return WriteFileAtomic(counter, append(content, 'x'))
+			})
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if content, _ := os.ReadFile(counter); len(content) != 8 {
+		t.Errorf("counter = %q, want 8 updates", content)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(file *os.File) error {
+	return errors.ErrUnsupported
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
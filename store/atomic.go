@@ -0,0 +1,230 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrWrongType 键的值类型不支持该操作，如对非数字执行 IncrBy
+	ErrWrongType = errors.New("store: operation against a value of the wrong type")
+	// ErrOutOfRange 运算结果溢出或不是有限数
+	ErrOutOfRange = errors.New("store: result out of range")
+)
+
+// expireAt 按 MemoryStore 的约定换算 ttl：-1 永不过期，KeepTTL 保留原有过期时间（键不存在时永不过期）
+func (ms *MemoryStore) expireAt(ttl time.Duration) time.Time {
+	if ttl == NoExpiration || ttl == KeepTTL {
+		return time.Time{}
+	}
+	return ms.cache.clock.Now().Add(ttl)
+}
+
+// update 以 MemoryStore 的 ttl 约定原子地更新键
+func (ms *MemoryStore) update(key string, ttl time.Duration, fn func(old any, exists bool) (any, updateAction)) (any, bool) {
+	return ms.cache.update(key, ms.expireAt(ttl), ttl == KeepTTL, fn)
+}
+
+// Update 在分片锁下原子地读取并更新键。fn 收到当前未过期的值，返回新值和 keep，keep 为 false 时删除键。
+// ttl 为 -1 时永不过期，KeepTTL 时保留原有的过期时间。fn 执行期间持有分片锁，不能再访问 MemoryStore
+func (ms *MemoryStore) Update(key string, ttl time.Duration, fn func(old any, exists bool) (value any, keep bool)) (any, bool) {
+	return ms.update(key, ttl, func(old any, exists bool) (any, updateAction) {
+		value, keep := fn(old, exists)
+		if !keep {
+			return value, updateDelete
+		}
+		return value, updateStore
+	})
+}
+
+// SetNX 键不存在或已过期时设置并返回 true，否则不做修改并返回 false
+func (ms *MemoryStore) SetNX(key string, value any, ttl time.Duration) bool {
+	_, stored := ms.update(key, ttl, func(old any, exists bool) (any, updateAction) {
+		if exists {
+			return old, updateSkip
+		}
+		return value, updateStore
+	})
+	return stored
+}
+
+// GetSet 设置新值并返回旧值，键不存在时 exists 为 false
+func (ms *MemoryStore) GetSet(key string, value any, ttl time.Duration) (old any, exists bool) {
+	ms.update(key, ttl, func(current any, found bool) (any, updateAction) {
+		old, exists = current, found
+		return value, updateStore
+	})
+	return old, exists
+}
+
+// CompareAndSwap 键存在且当前值与 old 相等（reflect.DeepEqual）时替换为 new 并返回 true
+func (ms *MemoryStore) CompareAndSwap(key string, old, new any, ttl time.Duration) bool {
+	_, swapped := ms.update(key, ttl, func(current any, exists bool) (any, updateAction) {
+		if !exists || !reflect.DeepEqual(current, old) {
+			return current, updateSkip
+		}
+		return new, updateStore
+	})
+	return swapped
+}
+
+// IncrBy 将整数值加上 delta 并返回结果，键不存在时从 0 开始。
+// 已有的值可以是任意整数类型或十进制字符串，结果以 int64 存储；
+// 值不是整数时返回 ErrWrongType，溢出时返回 ErrOutOfRange，两种情况都不修改键
+func (ms *MemoryStore) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	var err error
+	value, _ := ms.update(key, ttl, func(old any, exists bool) (any, updateAction) {
+		var current int64
+		if exists {
+			if current, err = toInt64(old); err != nil {
+				return old, updateSkip
+			}
+		}
+		if delta > 0 && current > math.MaxInt64-delta || delta < 0 && current < math.MinInt64-delta {
+			err = fmt.Errorf("%w: %d + %d", ErrOutOfRange, current, delta)
+			return old, updateSkip
+		}
+		return current + delta, updateStore
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
+
+// DecrBy 将整数值减去 delta 并返回结果，见 IncrBy
+func (ms *MemoryStore) DecrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, fmt.Errorf("%w: decrement %d", ErrOutOfRange, delta)
+	}
+	return ms.IncrBy(key, -delta, ttl)
+}
+
+// IncrByFloat 将数值加上 delta 并返回结果，键不存在时从 0 开始。
+// 已有的值可以是任意整数、浮点数类型或数字字符串，结果以 float64 存储；
+// 值不是数字时返回 ErrWrongType，结果为 NaN 或无穷大时返回 ErrOutOfRange，两种情况都不修改键
+func (ms *MemoryStore) IncrByFloat(key string, delta float64, ttl time.Duration) (float64, error) {
+	var err error
+	value, _ := ms.update(key, ttl, func(old any, exists bool) (any, updateAction) {
+		var current float64
+		if exists {
+			if current, err = toFloat64(old); err != nil {
+				return old, updateSkip
+			}
+		}
+		result := current + delta
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			err = fmt.Errorf("%w: %g + %g", ErrOutOfRange, current, delta)
+			return old, updateSkip
+		}
+		return result, updateStore
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value.(float64), nil
+}
+
+// DecrByFloat 将数值减去 delta 并返回结果，见 IncrByFloat
+func (ms *MemoryStore) DecrByFloat(key string, delta float64, ttl time.Duration) (float64, error) {
+	return ms.IncrByFloat(key, -delta, ttl)
+}
+
+// Append 在字符串或 []byte 值后追加 suffix 并返回追加后的字节长度，键不存在时以 suffix 作为字符串值。
+// []byte 值追加时复制到新的切片，不修改调用方持有的切片；其他类型返回 ErrWrongType
+func (ms *MemoryStore) Append(key string, suffix string, ttl time.Duration) (int, error) {
+	var err error
+	var length int
+	ms.update(key, ttl, func(old any, exists bool) (any, updateAction) {
+		if !exists {
+			length = len(suffix)
+			return suffix, updateStore
+		}
+		switch v := old.(type) {
+		case string:
+			length = len(v) + len(suffix)
+			return v + suffix, updateStore
+		case []byte:
+			appended := make([]byte, len(v), len(v)+len(suffix))
+			copy(appended, v)
+			appended = append(appended, suffix...)
+			length = len(appended)
+			return appended, updateStore
+		default:
+			err = fmt.Errorf("%w: append to %T", ErrWrongType, old)
+			return old, updateSkip
+		}
+	})
+	return length, err
+}
+
+// toInt64 将整数或十进制字符串转换为 int64
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return uintToInt64(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uintToInt64(v)
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q is not an integer", ErrWrongType, v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%w: %T is not an integer", ErrWrongType, value)
+	}
+}
+
+func uintToInt64(v uint64) (int64, error) {
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %d overflows int64", ErrOutOfRange, v)
+	}
+	return int64(v), nil
+}
+
+// toFloat64 将整数、浮点数或数字字符串转换为 float64
+func toFloat64(value any) (float64, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case uint:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("%w: %q is not a number", ErrWrongType, v)
+		}
+		return f, nil
+	default:
+		n, err := toInt64(value)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %T is not a number", ErrWrongType, value)
+		}
+		return float64(n), nil
+	}
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+func TestMemoryStore_IncrBy(t *testing.T) {
+	ms := NewMemoryStore(4, 10, time.Second)
+	defer ms.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ms.IncrBy("counter", 2, -1)
+				ms.DecrBy("counter", 1, -1)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _, _ := ms.Get("counter", false); value != int64(5000) {
+		t.Errorf("counter = %v, want 5000", value)
+	}
+
+	ms.Set("text", "41", -1)
+	if n, err := ms.IncrBy("text", 1, -1); err != nil || n != 42 {
+		t.Errorf("IncrBy numeric string = %d, %v", n, err)
+	}
+	ms.Set("word", "abc", -1)
+	if _, err := ms.IncrBy("word", 1, -1); !errors.Is(err, ErrWrongType) {
+		t.Errorf("IncrBy non-numeric: %v", err)
+	}
+	ms.Set("max", int64(math.MaxInt64), -1)
+	if _, err := ms.IncrBy("max", 1, -1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("IncrBy overflow: %v", err)
+	}
+	if value, _, _ := ms.Get("max", false); value != int64(math.MaxInt64) {
+		t.Errorf("overflow modified value: %v", value)
+	}
+	if _, err := ms.DecrBy("counter", math.MinInt64, -1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("DecrBy MinInt64: %v", err)
+	}
+
+	if f, err := ms.IncrByFloat("ratio", 1.5, -1); err != nil || f != 1.5 {
+		t.Errorf("IncrByFloat = %g, %v", f, err)
+	}
+	if f, err := ms.DecrByFloat("ratio", 0.25, -1); err != nil || f != 1.25 {
+		t.Errorf("DecrByFloat = %g, %v", f, err)
+	}
+	if f, err := ms.IncrByFloat("counter", 0.5, -1); err != nil || f != 5000.5 {
+		t.Errorf("IncrByFloat on integer = %g, %v", f, err)
+	}
+	if _, err := ms.IncrByFloat("ratio", math.Inf(1), -1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("IncrByFloat infinity: %v", err)
+	}
+}
+
+func TestMemoryStore_ConditionalWrites(t *testing.T) {
+	ms := NewMemoryStore(4, 10, time.Second)
+	defer ms.Close()
+
+	// SetNX 作为锁：并发时只有一个成功
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ms.SetNX("lock", i, time.Minute) {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if winners != 1 {
+		t.Errorf("SetNX winners = %d, want 1", winners)
+	}
+
+	if old, exists := ms.GetSet("token", "a", -1); exists || old != nil {
+		t.Errorf("GetSet missing = %v, %v", old, exists)
+	}
+	if old, exists := ms.GetSet("token", "b", -1); !exists || old != "a" {
+		t.Errorf("GetSet = %v, %v", old, exists)
+	}
+
+	if ms.CompareAndSwap("token", "a", "c", -1) {
+		t.Errorf("CompareAndSwap with stale value succeeded")
+	}
+	if !ms.CompareAndSwap("token", "b", "c", -1) {
+		t.Errorf("CompareAndSwap failed")
+	}
+	if ms.CompareAndSwap("missing", nil, "x", -1) {
+		t.Errorf("CompareAndSwap on missing key succeeded")
+	}
+	ms.Set("bytes", []byte("ab"), -1)
+	if !ms.CompareAndSwap("bytes", []byte("ab"), []byte("cd"), -1) {
+		t.Errorf("CompareAndSwap on []byte failed")
+	}
+
+	if n, err := ms.Append("log", "ab", -1); err != nil || n != 2 {
+		t.Errorf("Append missing = %d, %v", n, err)
+	}
+	if n, err := ms.Append("log", "cd", -1); err != nil || n != 4 {
+		t.Errorf("Append = %d, %v", n, err)
+	}
+	original := []byte("cd")
+	ms.Set("bytes", original[:1], -1)
+	if n, err := ms.Append("bytes", "x", -1); err != nil || n != 2 || string(original) != "cd" {
+		t.Errorf("Append []byte = %d, %v; caller slice now %q", n, err, original)
+	}
+	if _, err := ms.Append("counter", "x", -1); err != nil {
+		t.Errorf("Append to new key: %v", err)
+	}
+	ms.Set("number", 1, -1)
+	if _, err := ms.Append("number", "x", -1); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Append to int: %v", err)
+	}
+
+	if value, _, _ := ms.Get("log", false); value != "abcd" {
+		t.Errorf("log = %v", value)
+	}
+	if _, kept := ms.Update("log", KeepTTL, func(old any, exists bool) (any, bool) { return nil, false }); kept {
+		t.Errorf("Update delete reported kept")
+	}
+	if _, _, ok := ms.Get("log", false); ok {
+		t.Errorf("Update did not delete key")
+	}
+}
+
+func TestMemoryStore_UpdateTTL(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	ms := NewMemoryStore(4, 10, time.Second, WithClock(clock))
+	defer ms.Close()
+
+	ms.Set("key", 1, 10*time.Second)
+	clock.Set(clock.Now().Add(4 * time.Second))
+	ms.IncrBy("key", 1, KeepTTL)
+	if value, seconds, _ := ms.Get("key", false); value != int64(2) || seconds != 6 {
+		t.Errorf("KeepTTL: value %v, ttl %d; want 2, 6", value, seconds)
+	}
+	ms.Update("key", time.Minute, func(old any, exists bool) (any, bool) { return old.(int64) * 10, true })
+	if value, seconds, _ := ms.Get("key", false); value != int64(20) || seconds != 60 {
+		t.Errorf("new TTL: value %v, ttl %d; want 20, 60", value, seconds)
+	}
+	ms.Append("key2", "x", KeepTTL)
+	if _, seconds, _ := ms.Get("key2", false); seconds != -1 {
+		t.Errorf("KeepTTL on new key: ttl %d, want -1", seconds)
+	}
+
+	// 已过期的键视为不存在
+	ms.Set("lock", "a", time.Second)
+	clock.Set(clock.Now().Add(time.Second))
+	if !ms.SetNX("lock", "b", -1) {
+		t.Errorf("SetNX over expired key failed")
+	}
+
+	c := NewCache[string, int](WithClock(clock))
+	defer c.Close()
+	c.Set("n", 1, time.Minute)
+	c.Set("n", 2, KeepTTL)
+	if value, kept := c.Update("n", KeepTTL, func(old int, exists bool) (int, bool) { return old + 1, exists }); !kept || value != 3 {
+		t.Errorf("Cache.Update = %d, %v", value, kept)
+	}
+	_, expireAt, _ := c.get("n")
+	if expireAt.Sub(clock.Now()) != time.Minute {
+		t.Errorf("Cache KeepTTL expireAt in %v, want 1m", expireAt.Sub(clock.Now()))
+	}
+}
@@ -0,0 +1,594 @@
+package store
+
+import (
+	"fmt"
+	"hash/fnv"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+const (
+	// NoExpiration 永不过期，ttl <= 0 时均视为永不过期
+	NoExpiration time.Duration = -1
+	// KeepTTL 更新已有的键时保留原有的过期时间，键不存在时永不过期
+	KeepTTL time.Duration = -2
+)
+
+// updateAction Update 回调决定的写入方式
+type updateAction uint8
+
+const (
+	updateStore  updateAction = iota // 写入新值
+	updateDelete                     // 删除键
+	updateSkip                       // 保持不变
+)
+
+// cacheEntry 缓存条目，expireAt 为零值表示永不过期
+type cacheEntry[V any] struct {
+	value     V
+	expireAt  time.Time
+	refreshAt time.Time     // GetOrLoad 在此之后返回旧值并后台刷新，零值表示不刷新
+	ttl       time.Duration // 滑动过期时每次读取顺延的时长，0 表示不顺延
+	cost      int64
+	version   uint64 // 每次写入递增，修改过期时间产生的副本沿用原值
+}
+
+func (e *cacheEntry[V]) stale(now time.Time) bool {
+	return !e.refreshAt.IsZero() && !now.Before(e.refreshAt)
+}
+
+func (e *cacheEntry[V]) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+// cacheShard 用于分片存储数据，每个分片维护独立锁
+type cacheShard[K comparable, V any] struct {
+	sync.RWMutex
+	items    map[K]*cacheEntry[V]
+	loading  map[K]uint64      // 进行中的 GetOrLoad 开始时记录的代数，写入或删除键时移除使加载结果失效
+	policyMu sync.Mutex        // 读锁下也会更新淘汰策略，需要单独加锁
+	policy   evictionPolicy[K] // 未设置容量上限时为 nil
+}
+
+// eviction 待回调的移除记录
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// Cache 类型安全的分片缓存，过期键由时间轮异步清理，读取时也会检查过期时间。
+// 设置容量上限后，每个分片按淘汰策略选择淘汰的键，超出的总量优先从写入的分片淘汰
+type Cache[K comparable, V any] struct {
+	shards     []cacheShard[K, V]
+	hasher     func(key K) uint64
+	wheel      *TimeWheel[K]
+	clock      timeutil.Clock
+	maxEntries int64 // 所有分片的条目数上限，0 表示不限
+	maxCost    int64 // 所有分片的成本上限，0 表示不限
+	entries    atomic.Int64
+	costs      atomic.Int64
+	cursor     atomic.Uint64 // 写入的分片无法淘汰时，从此处开始轮流淘汰其他分片
+	cost       func(value V) int64
+	onEvict    func(key K, value V, reason EvictionReason)
+	sliding    bool
+	versions   atomic.Uint64
+	loads      loadGroup[K, V]
+}
+
+// NewCache 创建缓存，WithHasher、WithCost、WithEvictionCallback 的类型与 K、V 不一致时 panic
+func NewCache[K comparable, V any](opts ...Option) *Cache[K, V] {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.validate()
+
+	c := &Cache[K, V]{
+		shards:     make([]cacheShard[K, V], o.shardCount),
+		hasher:     defaultHash[K],
+		clock:      o.clock,
+		maxEntries: int64(o.maxEntries),
+		maxCost:    o.maxCost,
+		sliding:    o.sliding,
+		loads:      loadGroup[K, V]{ttl: o.loadTTL, refreshAfter: o.refreshAfter, negativeTTL: o.negativeTTL},
+	}
+	assertOption(o.hasher, &c.hasher)
+	assertOption(o.cost, &c.cost)
+	assertOption(o.onEvict, &c.onEvict)
+	for i := range c.shards {
+		c.shards[i].items = make(map[K]*cacheEntry[V])
+		if c.maxEntries > 0 || c.maxCost > 0 {
+			c.shards[i].policy = newEvictionPolicy(o.policy, ceilDiv(o.maxEntries, o.shardCount), c.hasher)
+		}
+	}
+
+	// 创建并启动时间轮
+	c.wheel = newTimeWheel(o.shardCount, o.slotCount, o.tickInterval, o.clock, c.hasher, c.expire)
+	c.wheel.Start()
+	return c
+}
+
+// assertOption 将以 any 保存的泛型配置断言为目标类型，类型不一致时 panic
+func assertOption[T any](option any, target *T) {
+	if option == nil {
+		return
+	}
+	value, ok := option.(T)
+	if !ok {
+		panic(fmt.Sprintf("store: option %T does not match %T", option, *target))
+	}
+	*target = value
+}
+
+func ceilDiv[T int | int64](total T, parts T) T {
+	return (total + parts - 1) / parts
+}
+
+// defaultHash 字符串和整数直接哈希，其他类型按 fmt.Sprint 的结果哈希
+func defaultHash[K comparable](key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return hashString(k)
+	case int:
+		return mix64(uint64(k))
+	case int8:
+		return mix64(uint64(k))
+	case int16:
+		return mix64(uint64(k))
+	case int32:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case uint:
+		return mix64(uint64(k))
+	case uint8:
+		return mix64(uint64(k))
+	case uint16:
+		return mix64(uint64(k))
+	case uint32:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case uintptr:
+		return mix64(uint64(k))
+	default:
+		return hashString(fmt.Sprint(key))
+	}
+}
+
+func hashString(s string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(s))
+	return hash.Sum64()
+}
+
+// mix64 splitmix64 的混合步骤，使连续整数均匀分布到各分片
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// getShard 获取对应分片
+func (c *Cache[K, V]) getShard(key K) *cacheShard[K, V] {
+	return &c.shards[c.hasher(key)%uint64(len(c.shards))]
+}
+
+// expireAt 将 ttl 换算为过期时间，ttl <= 0 时返回零值
+func (c *Cache[K, V]) expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return c.clock.Now().Add(ttl)
+}
+
+// Get 获取未过期的值
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	value, _, ok := c.get(key)
+	return value, ok
+}
+
+func (c *Cache[K, V]) get(key K) (V, time.Time, bool) {
+	entry, ok := c.lookup(key, c.clock.Now())
+	if !ok {
+		var zero V
+		return zero, time.Time{}, false
+	}
+	return entry.value, entry.expireAt, true
+}
+
+// lookup 返回未过期的条目并记录访问，滑动过期时顺延过期时间。
+// 条目写入后不再修改，更新时整体替换，因此可以在锁外读取
+func (c *Cache[K, V]) lookup(key K, now time.Time) (*cacheEntry[V], bool) {
+	shard := c.getShard(key)
+	lock, unlock := shard.RLock, shard.RUnlock
+	if c.sliding {
+		lock, unlock = shard.Lock, shard.Unlock
+	}
+	lock()
+	defer unlock()
+
+	entry, ok := shard.items[key]
+	if !ok || entry.expired(now) {
+		return nil, false
+	}
+	entry = c.slide(shard, key, entry, now)
+	if shard.policy != nil {
+		shard.policyMu.Lock()
+		shard.policy.access(key)
+		shard.policyMu.Unlock()
+	}
+	return entry, true
+}
+
+// Set 设置键值对，ttl 为 KeepTTL 时保留已有键的过期时间，其余 ttl <= 0 时永不过期
+func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
+	if ttl == KeepTTL {
+		c.update(key, time.Time{}, true, func(V, bool) (V, updateAction) { return value, updateStore })
+		return
+	}
+	c.setUntil(key, value, c.expireAt(ttl))
+}
+
+// Update 在分片写锁下原子地读取并更新键。fn 收到当前未过期的值，返回新值和 keep，keep 为 false 时删除键。
+// ttl 为新值的过期时间，KeepTTL 时保留原有的过期时间。fn 执行期间持有分片锁，不能再访问缓存
+func (c *Cache[K, V]) Update(key K, ttl time.Duration, fn func(old V, exists bool) (value V, keep bool)) (V, bool) {
+	return c.update(key, c.expireAt(ttl), ttl == KeepTTL, func(old V, exists bool) (V, updateAction) {
+		value, keep := fn(old, exists)
+		if !keep {
+			return value, updateDelete
+		}
+		return value, updateStore
+	})
+}
+
+// update 在分片写锁下按 fn 的结果写入、删除或保持键不变，keepTTL 为 true 且键存在时沿用原有的过期时间。
+// 返回 fn 给出的值以及键是否被写入
+func (c *Cache[K, V]) update(key K, expireAt time.Time, keepTTL bool, fn func(old V, exists bool) (V, updateAction)) (V, bool) {
+	shard := c.getShard(key)
+	var evictions []eviction[K, V]
+	value, action := func() (V, updateAction) {
+		shard.Lock()
+		defer shard.Unlock()
+
+		var old V
+		entry, found := shard.items[key]
+		exists := found && !entry.expired(c.clock.Now())
+		if exists {
+			old = entry.value
+		}
+		value, action := fn(old, exists)
+		switch {
+		case action == updateStore:
+			updated := c.newEntry(value, expireAt)
+			if keepTTL && exists {
+				updated.expireAt, updated.ttl = entry.expireAt, entry.ttl
+			}
+			evictions = c.store(shard, key, updated, nil)
+		case action == updateDelete:
+			c.supersede(shard, key)
+			if found {
+				c.unlink(shard, key, entry)
+				evictions = c.record(nil, key, entry, EvictionDeleted)
+			}
+		}
+		return value, action
+	}()
+
+	c.notify(c.shrink(shard, evictions))
+	return value, action == updateStore
+}
+
+// setUntil 设置键值对，expireAt 为零值时永不过期
+func (c *Cache[K, V]) setUntil(key K, value V, expireAt time.Time) {
+	shard := c.getShard(key)
+	c.notify(c.shrink(shard, c.storeLocked(shard, key, c.newEntry(value, expireAt))))
+}
+
+// storeLocked 获取分片写锁后写入条目，以 defer 解锁，淘汰策略等 panic 时不会使分片一直处于锁定状态
+func (c *Cache[K, V]) storeLocked(shard *cacheShard[K, V], key K, entry *cacheEntry[V]) []eviction[K, V] {
+	shard.Lock()
+	defer shard.Unlock()
+	return c.store(shard, key, entry, nil)
+}
+
+// newEntry 创建条目并计算成本，滑动过期时记录顺延的时长
+func (c *Cache[K, V]) newEntry(value V, expireAt time.Time) *cacheEntry[V] {
+	entry := &cacheEntry[V]{value: value, expireAt: expireAt, cost: 1, version: c.versions.Add(1)}
+	if c.cost != nil {
+		entry.cost = c.cost(value)
+	}
+	if c.sliding && !expireAt.IsZero() {
+		entry.ttl = expireAt.Sub(c.clock.Now())
+	}
+	return entry
+}
+
+// store 写入条目、同步时间轮，总量超出上限时先从本分片淘汰其他键，返回需要回调的移除记录。
+// 调用方需持有分片写锁，解锁后调用 shrink 处理本分片无法淘汰的部分
+func (c *Cache[K, V]) store(shard *cacheShard[K, V], key K, entry *cacheEntry[V], evictions []eviction[K, V]) []eviction[K, V] {
+	expireAt := entry.expireAt
+	c.supersede(shard, key)
+	old, exists := shard.items[key]
+	if exists {
+		c.costs.Add(-old.cost)
+		evictions = c.record(evictions, key, old, EvictionReplaced)
+	} else {
+		c.entries.Add(1)
+	}
+	shard.items[key] = entry
+	c.costs.Add(entry.cost)
+	if expireAt.IsZero() {
+		c.wheel.Remove(key)
+	} else {
+		c.wheel.Add(key, expireAt)
+	}
+
+	if shard.policy == nil {
+		return evictions
+	}
+	shard.policyMu.Lock()
+	defer shard.policyMu.Unlock()
+	if exists {
+		shard.policy.access(key)
+	} else {
+		shard.policy.add(key)
+	}
+	for evicted := true; evicted && len(shard.items) > 1 && c.overCapacity(); {
+		evictions, evicted = c.evictLocked(shard, evictions)
+	}
+	return evictions
+}
+
+// shrink 在分片锁之外从其他分片轮流淘汰，直到总量回到上限以内，最后才淘汰刚写入的分片
+func (c *Cache[K, V]) shrink(written *cacheShard[K, V], evictions []eviction[K, V]) []eviction[K, V] {
+	if written.policy == nil || !c.overCapacity() {
+		return evictions
+	}
+	start := c.cursor.Add(1)
+	for evicted := true; evicted && c.overCapacity(); {
+		evicted = false
+		for i := range uint64(len(c.shards)) {
+			shard := &c.shards[(start+i)%uint64(len(c.shards))]
+			if shard == written || !c.overCapacity() {
+				continue
+			}
+			var ok bool
+			evictions, ok = c.evictOne(shard, evictions)
+			evicted = evicted || ok
+		}
+	}
+	for evicted := true; evicted && c.overCapacity(); {
+		evictions, evicted = c.evictOne(written, evictions)
+	}
+	return evictions
+}
+
+// evictOne 获取分片写锁后按淘汰策略淘汰一个键
+func (c *Cache[K, V]) evictOne(shard *cacheShard[K, V], evictions []eviction[K, V]) ([]eviction[K, V], bool) {
+	shard.Lock()
+	defer shard.Unlock()
+
+	shard.policyMu.Lock()
+	defer shard.policyMu.Unlock()
+	return c.evictLocked(shard, evictions)
+}
+
+// evictLocked 按淘汰策略淘汰一个键，调用方需持有分片写锁和策略锁
+func (c *Cache[K, V]) evictLocked(shard *cacheShard[K, V], evictions []eviction[K, V]) ([]eviction[K, V], bool) {
+	victim, ok := shard.policy.evict()
+	if !ok {
+		return evictions, false
+	}
+	evicted := shard.items[victim]
+	delete(shard.items, victim)
+	c.entries.Add(-1)
+	c.costs.Add(-evicted.cost)
+	c.wheel.Remove(victim)
+	return c.record(evictions, victim, evicted, EvictionCapacity), true
+}
+
+func (c *Cache[K, V]) overCapacity() bool {
+	return c.maxEntries > 0 && c.entries.Load() > c.maxEntries || c.maxCost > 0 && c.costs.Load() > c.maxCost
+}
+
+// record 在设置了回调时记录移除，已过期的条目无论因何移除都记为过期
+func (c *Cache[K, V]) record(evictions []eviction[K, V], key K, entry *cacheEntry[V], reason EvictionReason) []eviction[K, V] {
+	if c.onEvict == nil {
+		return evictions
+	}
+	if reason != EvictionCapacity && entry.expired(c.clock.Now()) {
+		reason = EvictionExpired
+	}
+	return append(evictions, eviction[K, V]{key: key, value: entry.value, reason: reason})
+}
+
+// notify 在分片锁之外回调
+func (c *Cache[K, V]) notify(evictions []eviction[K, V]) {
+	for _, e := range evictions {
+		c.onEvict(e.key, e.value, e.reason)
+	}
+}
+
+// unlink 删除条目并移除时间轮和淘汰策略中的记录，调用方需持有分片写锁
+func (c *Cache[K, V]) unlink(shard *cacheShard[K, V], key K, entry *cacheEntry[V]) {
+	delete(shard.items, key)
+	c.entries.Add(-1)
+	c.costs.Add(-entry.cost)
+	c.wheel.Remove(key)
+	if shard.policy != nil {
+		shard.policyMu.Lock()
+		shard.policy.remove(key)
+		shard.policyMu.Unlock()
+	}
+}
+
+// GetOrSet 键存在且未过期时返回已有的值和 true，否则设置 value 并返回 value 和 false
+func (c *Cache[K, V]) GetOrSet(key K, value V, ttl time.Duration) (V, bool) {
+	shard := c.getShard(key)
+	var evictions []eviction[K, V]
+	actual, loaded := func() (V, bool) {
+		shard.Lock()
+		defer shard.Unlock()
+
+		if entry, ok := shard.items[key]; ok && !entry.expired(c.clock.Now()) {
+			entry = c.slide(shard, key, entry, c.clock.Now())
+			if shard.policy != nil {
+				shard.policyMu.Lock()
+				shard.policy.access(key)
+				shard.policyMu.Unlock()
+			}
+			return entry.value, true
+		}
+		evictions = c.store(shard, key, c.newEntry(value, c.expireAt(ttl)), nil)
+		return value, false
+	}()
+
+	c.notify(c.shrink(shard, evictions))
+	return actual, loaded
+}
+
+// Delete 删除键并清除 GetOrLoad 缓存的加载错误，返回删除前键是否存在且未过期
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.loads.forget(key)
+	entry, ok := c.remove(key)
+	if !ok {
+		return false
+	}
+	c.notify(c.record(nil, key, entry, EvictionDeleted))
+	return !entry.expired(c.clock.Now())
+}
+
+// GetAndDelete 在同一次加锁中读取并删除未过期的键，并发调用时只有一个调用方能取得值
+func (c *Cache[K, V]) GetAndDelete(key K) (V, bool) {
+	value, _, ok := c.getAndDelete(key)
+	return value, ok
+}
+
+func (c *Cache[K, V]) getAndDelete(key K) (V, time.Time, bool) {
+	c.loads.forget(key)
+	entry, ok := func() (*cacheEntry[V], bool) {
+		shard := c.getShard(key)
+		shard.Lock()
+		defer shard.Unlock()
+
+		c.supersede(shard, key)
+		entry, ok := shard.items[key]
+		if !ok || entry.expired(c.clock.Now()) {
+			return nil, false
+		}
+		c.unlink(shard, key, entry)
+		return entry, true
+	}()
+	if !ok {
+		var zero V
+		return zero, time.Time{}, false
+	}
+	c.notify(c.record(nil, key, entry, EvictionDeleted))
+	return entry.value, entry.expireAt, true
+}
+
+// remove 在分片写锁下删除键，返回被删除的条目
+func (c *Cache[K, V]) remove(key K) (*cacheEntry[V], bool) {
+	shard := c.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	c.supersede(shard, key)
+	entry, ok := shard.items[key]
+	if ok {
+		c.unlink(shard, key, entry)
+	}
+	return entry, ok
+}
+
+// expire 时间轮到期回调，只删除确实已过期的键，提前到期的键重新加入时间轮
+func (c *Cache[K, V]) expire(keys []K) {
+	now := c.clock.Now()
+	var evictions []eviction[K, V]
+	for _, key := range keys {
+		if entry, ok := c.expireKey(key, now); ok {
+			evictions = c.record(evictions, key, entry, EvictionExpired)
+		}
+	}
+	c.notify(evictions)
+}
+
+// expireKey 在分片写锁下删除已过期的键，未到期的键重新加入时间轮
+func (c *Cache[K, V]) expireKey(key K, now time.Time) (*cacheEntry[V], bool) {
+	shard := c.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	entry, ok := shard.items[key]
+	if !ok || entry.expireAt.IsZero() {
+		return nil, false
+	}
+	if !entry.expired(now) {
+		c.wheel.Add(key, entry.expireAt)
+		return nil, false
+	}
+	c.unlink(shard, key, entry)
+	return entry, true
+}
+
+// All 遍历未过期的键值对。每个分片在快照后再回调，回调中可以安全地读写缓存
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		type item struct {
+			key   K
+			value V
+		}
+		for i := range c.shards {
+			shard := &c.shards[i]
+			now := c.clock.Now()
+			shard.RLock()
+			items := make([]item, 0, len(shard.items))
+			for key, entry := range shard.items {
+				if !entry.expired(now) {
+					items = append(items, item{key, entry.value})
+				}
+			}
+			shard.RUnlock()
+
+			for _, it := range items {
+				if !yield(it.key, it.value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys 遍历未过期的键
+func (c *Cache[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range c.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Cost 当前的总成本，未设置 WithCost 时等于 Len
+func (c *Cache[K, V]) Cost() int64 {
+	return c.costs.Load()
+}
+
+// Len 当前存储的条目数，包含已过期但尚未被时间轮清理的条目
+func (c *Cache[K, V]) Len() int {
+	return int(c.entries.Load())
+}
+
+// Close 停止时间轮
+func (c *Cache[K, V]) Close() {
+	c.wheel.Stop()
+}
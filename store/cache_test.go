@@ -0,0 +1,165 @@
+package store
+
+import (
+	"maps"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+type tenantKey struct {
+	tenant string
+	id     int64
+}
+
+func TestCache_TypedOperations(t *testing.T) {
+	c := NewCache[tenantKey, []string](WithShards(8), WithHasher(func(k tenantKey) uint64 {
+		return hashString(k.tenant) ^ mix64(uint64(k.id))
+	}))
+	defer c.Close()
+
+	key := tenantKey{"acme", 42}
+	if _, ok := c.Get(key); ok {
+		t.Fatalf("expected empty cache")
+	}
+	c.Set(key, []string{"a"}, NoExpiration)
+	if value, ok := c.Get(key); !ok || len(value) != 1 || value[0] != "a" {
+		t.Errorf("Get = %v, %v", value, ok)
+	}
+
+	if actual, loaded := c.GetOrSet(key, []string{"b"}, time.Minute); !loaded || actual[0] != "a" {
+		t.Errorf("GetOrSet existing = %v, %v", actual, loaded)
+	}
+	other := tenantKey{"acme", 43}
+	if actual, loaded := c.GetOrSet(other, []string{"b"}, time.Minute); loaded || actual[0] != "b" {
+		t.Errorf("GetOrSet missing = %v, %v", actual, loaded)
+	}
+
+	if !c.Delete(key) || c.Delete(key) {
+		t.Errorf("Delete should report whether the key existed")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len = %d, want 1", c.Len())
+	}
+}
+
+func TestCache_HasherMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for mismatched hasher")
+		}
+	}()
+	NewCache[int, string](WithHasher(func(k string) uint64 { return 0 }))
+}
+
+func TestCache_Iteration(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	c := NewCache[int, string](WithClock(clock))
+	defer c.Close()
+
+	want := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		c.Set(i, strconv.Itoa(i), time.Duration(i%2+1)*time.Minute)
+		want[i] = strconv.Itoa(i)
+	}
+	if got := maps.Collect(c.All()); !maps.Equal(got, want) {
+		t.Errorf("All() returned %d items, want %d", len(got), len(want))
+	}
+
+	// 过期条目在被时间轮清理前也不会出现在遍历中
+	clock.Set(clock.Now().Add(90 * time.Second))
+	count := 0
+	for key := range c.Keys() {
+		if key%2 == 0 {
+			t.Errorf("expired key %d in iteration", key)
+		}
+		// 回调中可以写缓存
+		c.Set(key+1000, "", NoExpiration)
+		if count++; count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("iteration yielded %d keys, want 10", count)
+	}
+}
+
+func TestCache_TimeWheelExpiry(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	c := NewCache[string, int](WithClock(clock), WithTimeWheel(10, time.Second))
+	defer c.Close()
+
+	c.Set("short", 1, 3*time.Second)
+	c.Set("long", 2, 25*time.Second) // 超过时间轮一圈
+	c.Set("renewed", 3, 2*time.Second)
+	c.Set("forever", 4, NoExpiration)
+	c.Set("renewed", 3, time.Hour) // 重新设置后旧槽位不应删除它
+
+	advanceUntil := func(done func() bool) {
+		for i := 0; !done(); i++ {
+			if i == 1000 {
+				t.Fatalf("time wheel did not collect keys, len %d", c.Len())
+			}
+			clock.Advance(time.Second)
+			time.Sleep(time.Millisecond)
+		}
+	}
+	advanceUntil(func() bool { return c.Len() == 3 })
+	if _, ok := c.Get("long"); !ok {
+		t.Errorf("long-lived key collected too early")
+	}
+	advanceUntil(func() bool { return c.Len() == 2 })
+	if _, ok := c.Get("renewed"); !ok {
+		t.Errorf("renewed key was collected by its old slot")
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Errorf("persistent key was collected")
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	c := NewCache[int, int](WithTimeWheel(8, time.Millisecond))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := i % 64
+				switch i % 4 {
+				case 0:
+					c.Set(key, g, time.Duration(i%5)*time.Millisecond)
+				case 1:
+					c.Get(key)
+				case 2:
+					c.GetOrSet(key, g, time.Millisecond)
+				case 3:
+					c.Delete(key)
+				}
+			}
+			for range c.All() {
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// BenchmarkCache_SetTTLParallel 并发写入带过期时间的键，衡量时间轮锁的竞争
+func BenchmarkCache_SetTTLParallel(b *testing.B) {
+	c := NewCache[int, int]()
+	defer c.Close()
+
+	var seed atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		key := int(seed.Add(1)) << 20
+		for i := 0; pb.Next(); i++ {
+			c.Set(key+i%4096, i, time.Minute)
+		}
+	})
+}
@@ -0,0 +1,362 @@
+package store
+
+import (
+	"container/list"
+)
+
+// EvictionPolicy 容量超限时的淘汰策略
+type EvictionPolicy uint8
+
+const (
+	// EvictLRU 淘汰最久未访问的条目
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU 淘汰访问次数最少的条目，次数相同时淘汰最久未访问的
+	EvictLFU
+	// EvictWTinyLFU 新条目先进入 1% 的 LRU 窗口，离开窗口时与主区的淘汰候选比较估算频率，频率更高者留下
+	EvictWTinyLFU
+)
+
+// EvictionReason 条目被移除的原因
+type EvictionReason uint8
+
+const (
+	// EvictionCapacity 超出条目数或成本上限被淘汰
+	EvictionCapacity EvictionReason = iota + 1
+	// EvictionExpired 过期
+	EvictionExpired
+	// EvictionDeleted 被显式删除
+	EvictionDeleted
+	// EvictionReplaced 被新值覆盖
+	EvictionReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionExpired:
+		return "expired"
+	case EvictionDeleted:
+		return "deleted"
+	case EvictionReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// evictionPolicy 分片内的淘汰策略，调用方需持有分片的策略锁
+type evictionPolicy[K comparable] interface {
+	// add 记录新增的键
+	add(key K)
+	// access 记录命中或更新
+	access(key K)
+	// remove 键被删除、过期时移除记录
+	remove(key K)
+	// evict 选择并移除一个淘汰的键，没有键时返回 false
+	evict() (K, bool)
+}
+
+func newEvictionPolicy[K comparable](policy EvictionPolicy, capacity int, hasher func(K) uint64) evictionPolicy[K] {
+	switch policy {
+	case EvictLFU:
+		return newLFUPolicy[K]()
+	case EvictWTinyLFU:
+		return newTinyLFUPolicy(capacity, hasher)
+	default:
+		return newLRUPolicy[K]()
+	}
+}
+
+// lruPolicy 链表头部为最近访问的键
+type lruPolicy[K comparable] struct {
+	order *list.List
+	items map[K]*list.Element
+}
+
+func newLRUPolicy[K comparable]() *lruPolicy[K] {
+	return &lruPolicy[K]{order: list.New(), items: make(map[K]*list.Element)}
+}
+
+func (p *lruPolicy[K]) add(key K) {
+	p.items[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy[K]) access(key K) {
+	if element, ok := p.items[key]; ok {
+		p.order.MoveToFront(element)
+	}
+}
+
+func (p *lruPolicy[K]) remove(key K) {
+	if element, ok := p.items[key]; ok {
+		p.order.Remove(element)
+		delete(p.items, key)
+	}
+}
+
+func (p *lruPolicy[K]) evict() (K, bool) {
+	element := p.order.Back()
+	if element == nil {
+		var zero K
+		return zero, false
+	}
+	key := element.Value.(K)
+	p.order.Remove(element)
+	delete(p.items, key)
+	return key, true
+}
+
+// lfuBucket 访问次数相同的键，链表头部为最近访问的键
+type lfuBucket[K comparable] struct {
+	count int
+	keys  *list.List
+}
+
+// lfuItem 键所在的频率桶及其在桶中的位置
+type lfuItem[K comparable] struct {
+	bucket  *list.Element // buckets 中的元素，值为 *lfuBucket[K]
+	element *list.Element // bucket.keys 中的元素
+}
+
+// lfuPolicy O(1) LFU，频率桶按访问次数升序排列。
+// 新加入的键不会被立即淘汰，否则缓存满后访问次数为 1 的新键总是第一个被淘汰，再也无法接纳新键
+type lfuPolicy[K comparable] struct {
+	buckets *list.List
+	items   map[K]lfuItem[K]
+	newest  K
+}
+
+func newLFUPolicy[K comparable]() *lfuPolicy[K] {
+	return &lfuPolicy[K]{buckets: list.New(), items: make(map[K]lfuItem[K])}
+}
+
+func (p *lfuPolicy[K]) add(key K) {
+	first := p.buckets.Front()
+	if first == nil || first.Value.(*lfuBucket[K]).count != 1 {
+		first = p.buckets.PushFront(&lfuBucket[K]{count: 1, keys: list.New()})
+	}
+	p.items[key] = lfuItem[K]{bucket: first, element: first.Value.(*lfuBucket[K]).keys.PushFront(key)}
+	p.newest = key
+}
+
+func (p *lfuPolicy[K]) access(key K) {
+	item, ok := p.items[key]
+	if !ok {
+		return
+	}
+	current := item.bucket.Value.(*lfuBucket[K])
+	next := item.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket[K]).count != current.count+1 {
+		next = p.buckets.InsertAfter(&lfuBucket[K]{count: current.count + 1, keys: list.New()}, item.bucket)
+	}
+	p.detach(item)
+	p.items[key] = lfuItem[K]{bucket: next, element: next.Value.(*lfuBucket[K]).keys.PushFront(key)}
+}
+
+func (p *lfuPolicy[K]) remove(key K) {
+	if item, ok := p.items[key]; ok {
+		p.detach(item)
+		delete(p.items, key)
+	}
+}
+
+// detach 从桶中移除键，桶为空时一并移除
+func (p *lfuPolicy[K]) detach(item lfuItem[K]) {
+	bucket := item.bucket.Value.(*lfuBucket[K])
+	bucket.keys.Remove(item.element)
+	if bucket.keys.Len() == 0 {
+		p.buckets.Remove(item.bucket)
+	}
+}
+
+func (p *lfuPolicy[K]) evict() (K, bool) {
+	first := p.buckets.Front()
+	if first == nil {
+		var zero K
+		return zero, false
+	}
+	element := first.Value.(*lfuBucket[K]).keys.Back()
+	if element.Value.(K) == p.newest && len(p.items) > 1 {
+		// 不淘汰最新的键：同一个桶中还有其他键时淘汰它前面的键，否则淘汰下一个桶中最久未访问的键
+		if prev := element.Prev(); prev != nil {
+			element = prev
+		} else if next := first.Next(); next != nil {
+			element = next.Value.(*lfuBucket[K]).keys.Back()
+		}
+	}
+	key := element.Value.(K)
+	p.remove(key)
+	return key, true
+}
+
+// tinyLFU 分区
+const (
+	segmentWindow uint8 = iota
+	segmentProbation
+	segmentProtected
+)
+
+// tinyLFUItem 键及其所在分区
+type tinyLFUItem[K comparable] struct {
+	key     K
+	segment uint8
+}
+
+// tinyLFUPolicy W-TinyLFU：1% 的 LRU 窗口加上分段 LRU 主区（20% 试用区、80% 保护区），
+// 条目离开窗口时与试用区的淘汰候选按 Count-Min Sketch 估算的频率比较，频率更高者留下
+type tinyLFUPolicy[K comparable] struct {
+	hasher    func(K) uint64
+	sketch    *frequencySketch
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	items     map[K]*list.Element // 值为 *tinyLFUItem[K]
+}
+
+func newTinyLFUPolicy[K comparable](capacity int, hasher func(K) uint64) *tinyLFUPolicy[K] {
+	return &tinyLFUPolicy[K]{
+		hasher:    hasher,
+		sketch:    newFrequencySketch(capacity),
+		window:    list.New(),
+		probation: list.New(),
+		protected: list.New(),
+		items:     make(map[K]*list.Element),
+	}
+}
+
+func (p *tinyLFUPolicy[K]) segment(id uint8) *list.List {
+	switch id {
+	case segmentProbation:
+		return p.probation
+	case segmentProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+func (p *tinyLFUPolicy[K]) add(key K) {
+	p.sketch.increment(p.hasher(key))
+	p.items[key] = p.window.PushFront(&tinyLFUItem[K]{key: key, segment: segmentWindow})
+}
+
+func (p *tinyLFUPolicy[K]) access(key K) {
+	p.sketch.increment(p.hasher(key))
+	element, ok := p.items[key]
+	if !ok {
+		return
+	}
+	item := element.Value.(*tinyLFUItem[K])
+	switch item.segment {
+	case segmentProbation:
+		// 试用区再次命中后晋升到保护区，保护区超出配额时最久未访问的条目降级回试用区
+		p.move(element, segmentProtected)
+		if mainSize := p.probation.Len() + p.protected.Len(); p.protected.Len() > mainSize*8/10 {
+			p.move(p.protected.Back(), segmentProbation)
+		}
+	default:
+		p.segment(item.segment).MoveToFront(element)
+	}
+}
+
+// move 将元素移到目标分区头部
+func (p *tinyLFUPolicy[K]) move(element *list.Element, segment uint8) {
+	item := element.Value.(*tinyLFUItem[K])
+	p.segment(item.segment).Remove(element)
+	item.segment = segment
+	p.items[item.key] = p.segment(segment).PushFront(item)
+}
+
+func (p *tinyLFUPolicy[K]) remove(key K) {
+	if element, ok := p.items[key]; ok {
+		p.segment(element.Value.(*tinyLFUItem[K]).segment).Remove(element)
+		delete(p.items, key)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) evict() (K, bool) {
+	// 窗口超出配额时，最久未访问的窗口条目进入试用区成为候选
+	var candidate *list.Element
+	windowMax := max(1, len(p.items)/100)
+	for p.window.Len() > windowMax {
+		p.move(p.window.Back(), segmentProbation)
+		candidate = p.probation.Front()
+	}
+
+	victim := p.probation.Back()
+	if victim == candidate {
+		victim = p.protected.Back()
+	}
+	switch {
+	case victim == nil && candidate == nil:
+		victim = p.window.Back()
+	case victim == nil:
+		victim = candidate
+	case candidate != nil:
+		candidateKey := candidate.Value.(*tinyLFUItem[K]).key
+		victimKey := victim.Value.(*tinyLFUItem[K]).key
+		if p.sketch.estimate(p.hasher(candidateKey)) <= p.sketch.estimate(p.hasher(victimKey)) {
+			victim = candidate
+		}
+	}
+	if victim == nil {
+		var zero K
+		return zero, false
+	}
+	key := victim.Value.(*tinyLFUItem[K]).key
+	p.remove(key)
+	return key, true
+}
+
+// frequencySketch 4 位计数器的 Count-Min Sketch，每行 4 倍容量个计数器，
+// 计数总量达到 10 倍容量时所有计数减半，使频率随时间衰减
+type frequencySketch struct {
+	counters  [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newFrequencySketch(capacity int) *frequencySketch {
+	capacity = max(capacity, 16)
+	width := 64
+	for width < capacity*4 {
+		width <<= 1
+	}
+	s := &frequencySketch{mask: uint64(width - 1), resetAt: capacity * 10}
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index 由同一个哈希派生出每一行的位置
+func (s *frequencySketch) index(hash uint64, row int) uint64 {
+	hash = mix64(hash + uint64(row)*0x9e3779b97f4a7c15)
+	return hash & s.mask
+}
+
+func (s *frequencySketch) increment(hash uint64) {
+	for row := range s.counters {
+		if i := s.index(hash, row); s.counters[row][i] < 15 {
+			s.counters[row][i]++
+		}
+	}
+	if s.additions++; s.additions >= s.resetAt {
+		for row := range s.counters {
+			for i := range s.counters[row] {
+				s.counters[row][i] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *frequencySketch) estimate(hash uint64) uint8 {
+	estimate := uint8(15)
+	for row := range s.counters {
+		estimate = min(estimate, s.counters[row][s.index(hash, row)])
+	}
+	return estimate
+}
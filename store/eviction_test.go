@@ -0,0 +1,297 @@
+package store
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// evictionLog 记录淘汰回调
+type evictionLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *evictionLog) callback(key string, value int, reason EvictionReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, fmt.Sprintf("%s=%d:%s", key, value, reason))
+}
+
+func (l *evictionLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := l.events
+	l.events = nil
+	return events
+}
+
+func expectEvents(t *testing.T, log *evictionLog, want ...string) {
+	t.Helper()
+	if got := log.take(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("evictions = %v, want %v", got, want)
+	}
+}
+
+func TestCache_LRU(t *testing.T) {
+	log := &evictionLog{}
+	c := NewCache[string, int](WithShards(1), WithMaxEntries(3), WithEvictionCallback(log.callback))
+	defer c.Close()
+
+	c.Set("a", 1, NoExpiration)
+	c.Set("b", 2, NoExpiration)
+	c.Set("c", 3, NoExpiration)
+	c.Get("a")
+	c.Set("d", 4, NoExpiration)
+	expectEvents(t, log, "b=2:capacity")
+	c.Set("c", 30, NoExpiration)
+	c.Set("e", 5, NoExpiration)
+	expectEvents(t, log, "c=3:replaced", "a=1:capacity")
+	if c.Len() != 3 {
+		t.Errorf("Len = %d, want 3", c.Len())
+	}
+}
+
+func TestCache_LFU(t *testing.T) {
+	log := &evictionLog{}
+	c := NewCache[string, int](WithShards(1), WithMaxEntries(3), WithEvictionPolicy(EvictLFU), WithEvictionCallback(log.callback))
+	defer c.Close()
+
+	c.Set("a", 1, NoExpiration)
+	c.Set("b", 2, NoExpiration)
+	c.Set("c", 3, NoExpiration)
+	for i := 0; i < 3; i++ {
+		c.Get("a")
+		c.Get("c")
+	}
+	c.Get("b")
+	c.Set("d", 4, NoExpiration)
+	expectEvents(t, log, "b=2:capacity")
+	// 次数相同时淘汰最久未访问的
+	c.Set("e", 5, NoExpiration)
+	expectEvents(t, log, "d=4:capacity")
+}
+
+func TestCache_LFU_CostIncrease(t *testing.T) {
+	log := &evictionLog{}
+	c := NewCache[string, int](WithShards(1), WithMaxCost(10), WithCost(func(v int) int64 { return int64(v) }),
+		WithEvictionPolicy(EvictLFU), WithEvictionCallback(log.callback))
+	defer c.Close()
+
+	// 最新的键与被更新的键同在唯一的频率桶中，更新使成本超限
+	c.Set("a", 4, NoExpiration)
+	c.Set("b", 4, NoExpiration)
+	c.Get("b")
+	c.Set("a", 7, NoExpiration)
+	expectEvents(t, log, "a=4:replaced", "a=7:capacity")
+	if value, ok := c.Get("b"); !ok || value != 4 || c.Cost() != 4 {
+		t.Errorf("Get(b) = %d, %v; cost %d", value, ok, c.Cost())
+	}
+
+	// 只剩最新的键时淘汰它自己
+	c.Set("b", 11, NoExpiration)
+	if c.Len() != 0 || c.Cost() != 0 {
+		t.Errorf("Len = %d, Cost = %d after oversized update", c.Len(), c.Cost())
+	}
+	c.Set("c", 1, NoExpiration)
+	if _, ok := c.Get("c"); !ok {
+		t.Errorf("shard unusable after oversized update")
+	}
+}
+
+func TestCache_WTinyLFU_ScanResistant(t *testing.T) {
+	// 每轮访问 50 个热点键后顺序扫描 200 个只出现一次的键：LRU 中热点键总被扫描挤出，W-TinyLFU 应保留热点键
+	hotHits := func(policy EvictionPolicy) int {
+		c := NewCache[int, int](WithShards(1), WithMaxEntries(100), WithEvictionPolicy(policy))
+		defer c.Close()
+
+		hits, scan := 0, 1000
+		for round := 0; round < 50; round++ {
+			for key := 0; key < 50; key++ {
+				if _, ok := c.Get(key); ok {
+					hits++
+				} else {
+					c.Set(key, key, NoExpiration)
+				}
+			}
+			for i := 0; i < 200; i++ {
+				scan++
+				c.Set(scan, scan, NoExpiration)
+			}
+			if c.Len() > 100 {
+				t.Fatalf("policy %d: Len = %d exceeds capacity", policy, c.Len())
+			}
+		}
+		return hits
+	}
+
+	if hits := hotHits(EvictLRU); hits != 0 {
+		t.Errorf("LRU hot hits = %d, want 0", hits)
+	}
+	if hits := hotHits(EvictWTinyLFU); hits < 2000 {
+		t.Errorf("W-TinyLFU hot hits = %d of 2450, want at least 2000", hits)
+	}
+}
+
+func TestCache_MaxCost(t *testing.T) {
+	log := &evictionLog{}
+	c := NewCache[string, int](WithShards(1), WithMaxCost(10), WithCost(func(v int) int64 { return int64(v) }),
+		WithEvictionCallback(log.callback))
+	defer c.Close()
+
+	c.Set("a", 4, NoExpiration)
+	c.Set("b", 4, NoExpiration)
+	if c.Cost() != 8 {
+		t.Errorf("Cost = %d, want 8", c.Cost())
+	}
+	c.Set("c", 5, NoExpiration)
+	expectEvents(t, log, "a=4:capacity")
+	c.Set("b", 1, NoExpiration)
+	expectEvents(t, log, "b=4:replaced")
+	if c.Cost() != 6 {
+		t.Errorf("Cost = %d, want 6", c.Cost())
+	}
+	// 单个条目超出上限时自身也会被淘汰
+	c.Set("huge", 11, NoExpiration)
+	if _, ok := c.Get("huge"); ok || c.Cost() > 10 {
+		t.Errorf("oversized entry kept, cost %d", c.Cost())
+	}
+}
+
+func TestCache_EvictionReasons(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	log := &evictionLog{}
+	var c *Cache[string, int]
+	c = NewCache[string, int](WithClock(clock), WithTimeWheel(10, time.Second), WithEvictionCallback(func(key string, value int, reason EvictionReason) {
+		log.callback(key, value, reason)
+		c.Get(key) // 回调在锁外执行，可以访问缓存
+	}))
+	defer c.Close()
+
+	c.Set("deleted", 1, NoExpiration)
+	c.Delete("deleted")
+	expectEvents(t, log, "deleted=1:deleted")
+
+	c.Set("expired", 2, time.Second)
+	for i := 0; c.Len() != 0; i++ {
+		if i == 1000 {
+			t.Fatalf("expired key was not collected")
+		}
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	expectEvents(t, log, "expired=2:expired")
+
+	// 覆盖已过期但未清理的条目记为过期
+	c.Set("stale", 3, time.Second)
+	clock.Set(clock.Now().Add(time.Second))
+	c.Set("stale", 4, NoExpiration)
+	expectEvents(t, log, "stale=3:expired")
+}
+
+// 测试默认 16 个分片时容量上限对所有分片整体生效
+func TestCache_MaxEntries_Sharded(t *testing.T) {
+	log := &evictionLog{}
+	c := NewCache[string, int](WithMaxEntries(1), WithEvictionCallback(log.callback))
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprint(i), i, NoExpiration)
+		if c.Len() != 1 {
+			t.Fatalf("Len = %d after %d sets, want 1", c.Len(), i+1)
+		}
+	}
+	if value, ok := c.Get("99"); !ok || value != 99 {
+		t.Errorf("Get(99) = %d, %v; want the last written key to stay", value, ok)
+	}
+	if events := log.take(); len(events) != 99 {
+		t.Errorf("got %d capacity evictions, want 99", len(events))
+	}
+
+	// 并发写入结束后总量同样不超过上限
+	bounded := NewCache[int, int](WithMaxEntries(10), WithMaxCost(20))
+	defer bounded.Close()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				bounded.Set(g*1000+i, i, NoExpiration)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if bounded.Len() > 10 || bounded.Cost() > 20 {
+		t.Errorf("Len = %d, Cost = %d; want at most 10 and 20", bounded.Len(), bounded.Cost())
+	}
+}
+
+func TestMemoryStore_MaxEntries(t *testing.T) {
+	ms := NewMemoryStore(4, 10, time.Second, WithMaxEntries(8))
+	defer ms.Close()
+
+	for i := 0; i < 100; i++ {
+		ms.Set(fmt.Sprint(i), i, -1)
+	}
+	if stored := ms.Stats()["totalStored"].(int); stored > 8 {
+		t.Errorf("totalStored = %d, want at most 8", stored)
+	}
+}
+
+// 命中率基准，使用合成的访问序列模拟常见负载（仓库未附带 ARC、LIRS 等公开 trace 文件）：
+// Zipf 分布、Zipf 混合 30% 顺序扫描、循环访问略多于容量的键
+func BenchmarkCache_HitRatio(b *testing.B) {
+	const capacity = 1000
+	traces := map[string]func(r *rand.Rand) func() int{
+		"zipf": func(r *rand.Rand) func() int {
+			zipf := rand.NewZipf(r, 1.01, 1, 1<<20)
+			return func() int { return int(zipf.Uint64()) }
+		},
+		"zipf+scan": func(r *rand.Rand) func() int {
+			zipf := rand.NewZipf(r, 1.01, 1, 1<<20)
+			scan, i := 1<<21, 0
+			return func() int {
+				if i++; i%1000 < 300 {
+					scan++
+					return scan
+				}
+				return int(zipf.Uint64())
+			}
+		},
+		"loop": func(r *rand.Rand) func() int {
+			i := 0
+			return func() int {
+				i++
+				return i % (capacity * 5 / 4)
+			}
+		},
+	}
+	policies := map[string]EvictionPolicy{"LRU": EvictLRU, "LFU": EvictLFU, "W-TinyLFU": EvictWTinyLFU}
+
+	for traceName, newTrace := range traces {
+		for policyName, policy := range policies {
+			b.Run(traceName+"/"+policyName, func(b *testing.B) {
+				c := NewCache[int, int](WithShards(1), WithMaxEntries(capacity), WithEvictionPolicy(policy))
+				defer c.Close()
+				next := newTrace(rand.New(rand.NewSource(1)))
+
+				hits := 0
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					key := next()
+					if _, ok := c.Get(key); ok {
+						hits++
+					} else {
+						c.Set(key, key, NoExpiration)
+					}
+				}
+				b.ReportMetric(float64(hits)*100/float64(b.N), "hit%")
+			})
+		}
+	}
+}
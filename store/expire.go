@@ -0,0 +1,102 @@
+package store
+
+import (
+	"time"
+)
+
+// Expire 修改未过期的键的过期时间为 ttl 之后，不改写值，ttl <= 0 时立即删除键。键不存在或已过期时返回 false
+func (c *Cache[K, V]) Expire(key K, ttl time.Duration) bool {
+	now := c.clock.Now()
+	return c.setExpiry(key, now.Add(max(ttl, 0)), now)
+}
+
+// ExpireAt 将未过期的键的过期时间设为 deadline，deadline 不晚于当前时间时立即删除键。键不存在或已过期时返回 false
+func (c *Cache[K, V]) ExpireAt(key K, deadline time.Time) bool {
+	now := c.clock.Now()
+	if deadline.Before(now) {
+		deadline = now
+	}
+	return c.setExpiry(key, deadline, now)
+}
+
+// Persist 移除键的过期时间，返回键是否存在且原本设置了过期时间
+func (c *Cache[K, V]) Persist(key K) bool {
+	shard := c.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	entry, ok := shard.items[key]
+	if !ok || entry.expireAt.IsZero() || entry.expired(c.clock.Now()) {
+		return false
+	}
+	c.reschedule(shard, key, entry, time.Time{}, 0)
+	return true
+}
+
+// TTL 返回键精确的剩余存活时间，永不过期的键返回 NoExpiration，键不存在或已过期时返回 false
+func (c *Cache[K, V]) TTL(key K) (time.Duration, bool) {
+	shard := c.getShard(key)
+	shard.RLock()
+	defer shard.RUnlock()
+
+	now := c.clock.Now()
+	entry, ok := shard.items[key]
+	if !ok || entry.expired(now) {
+		return 0, false
+	}
+	if entry.expireAt.IsZero() {
+		return NoExpiration, true
+	}
+	return entry.expireAt.Sub(now), true
+}
+
+// setExpiry 修改未过期的键的过期时间，expireAt 不晚于 now 时删除键
+func (c *Cache[K, V]) setExpiry(key K, expireAt time.Time, now time.Time) bool {
+	evictions, ok := c.setExpiryLocked(key, expireAt, now)
+	c.notify(evictions)
+	return ok
+}
+
+// setExpiryLocked 在分片写锁下修改过期时间，返回需要回调的移除记录
+func (c *Cache[K, V]) setExpiryLocked(key K, expireAt time.Time, now time.Time) ([]eviction[K, V], bool) {
+	shard := c.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	entry, ok := shard.items[key]
+	if !ok || entry.expired(now) {
+		return nil, false
+	}
+	if !expireAt.After(now) {
+		c.unlink(shard, key, entry)
+		return c.record(nil, key, entry, EvictionExpired), true
+	}
+	var ttl time.Duration
+	if c.sliding {
+		ttl = expireAt.Sub(now)
+	}
+	c.reschedule(shard, key, entry, expireAt, ttl)
+	return nil, true
+}
+
+// slide 滑动过期时将条目的过期时间顺延到 now 之后 ttl，返回当前条目，调用方需持有分片写锁
+func (c *Cache[K, V]) slide(shard *cacheShard[K, V], key K, entry *cacheEntry[V], now time.Time) *cacheEntry[V] {
+	if !c.sliding || entry.ttl <= 0 {
+		return entry
+	}
+	return c.reschedule(shard, key, entry, now.Add(entry.ttl), entry.ttl)
+}
+
+// reschedule 以新的过期时间替换条目并移动其在时间轮中的位置，expireAt 为零值时移出时间轮。
+// 条目可能正在锁外被读取，因此替换为副本而不是原地修改，调用方需持有分片写锁
+func (c *Cache[K, V]) reschedule(shard *cacheShard[K, V], key K, entry *cacheEntry[V], expireAt time.Time, ttl time.Duration) *cacheEntry[V] {
+	updated := *entry
+	updated.expireAt, updated.ttl = expireAt, ttl
+	shard.items[key] = &updated
+	if expireAt.IsZero() {
+		c.wheel.Remove(key)
+	} else {
+		c.wheel.Add(key, expireAt)
+	}
+	return &updated
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// wheelSlots 返回键在时间轮各槽位中出现的次数和记录的位置
+func wheelSlots[K comparable](w *TimeWheel[K], key K) (count int, tracked bool) {
+	for i := range w.shards {
+		shard := &w.shards[i]
+		shard.mu.Lock()
+		for _, slot := range shard.slots {
+			if _, ok := slot[key]; ok {
+				count++
+			}
+		}
+		_, inShard := shard.positions[key]
+		tracked = tracked || inShard
+		shard.mu.Unlock()
+	}
+	return count, tracked
+}
+
+func TestMemoryStore_ExpireCommands(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	ms := NewMemoryStore(4, 10, time.Second, WithClock(clock))
+	defer ms.Close()
+	wheel := ms.cache.wheel
+
+	if ms.Expire("missing", time.Second) || ms.Persist("missing") {
+		t.Errorf("TTL commands on missing key succeeded")
+	}
+	if _, ok := ms.TTL("missing"); ok {
+		t.Errorf("TTL on missing key reported ok")
+	}
+
+	ms.Set("key", "value", -1)
+	if ttl, ok := ms.TTL("key"); !ok || ttl != -1 {
+		t.Errorf("TTL of persistent key = %v, %v", ttl, ok)
+	}
+	if count, tracked := wheelSlots(wheel, "key"); count != 0 || tracked {
+		t.Errorf("persistent key in wheel: %d slots, tracked %v", count, tracked)
+	}
+
+	if !ms.Expire("key", 1500*time.Millisecond) {
+		t.Fatalf("Expire failed")
+	}
+	if ttl, _ := ms.TTL("key"); ttl != 1500*time.Millisecond {
+		t.Errorf("TTL = %v, want 1.5s", ttl)
+	}
+	ms.Expire("key", 25*time.Second)
+	if count, tracked := wheelSlots(wheel, "key"); count != 1 || !tracked {
+		t.Errorf("after Expire: %d slots, tracked %v", count, tracked)
+	}
+
+	deadline := clock.Now().Add(42 * time.Second)
+	ms.ExpireAt("key", deadline)
+	if ttl, _ := ms.TTL("key"); ttl != 42*time.Second {
+		t.Errorf("TTL after ExpireAt = %v, want 42s", ttl)
+	}
+	if count, _ := wheelSlots(wheel, "key"); count != 1 {
+		t.Errorf("after ExpireAt: %d slots", count)
+	}
+
+	if !ms.Persist("key") || ms.Persist("key") {
+		t.Errorf("Persist should succeed once")
+	}
+	if count, tracked := wheelSlots(wheel, "key"); count != 0 || tracked {
+		t.Errorf("after Persist: %d slots, tracked %v", count, tracked)
+	}
+	if value, seconds, ok := ms.Get("key", false); !ok || value != "value" || seconds != -1 {
+		t.Errorf("Get after Persist = %v, %d, %v", value, seconds, ok)
+	}
+
+	// 过去的时间点或非正的 ttl 立即删除
+	if !ms.ExpireAt("key", clock.Now().Add(-time.Second)) {
+		t.Errorf("ExpireAt in the past failed")
+	}
+	if _, _, ok := ms.Get("key", false); ok {
+		t.Errorf("key survived ExpireAt in the past")
+	}
+	ms.Set("other", 1, -1)
+	ms.Expire("other", 0)
+	if count, tracked := wheelSlots(wheel, "other"); ms.Stats()["totalStored"] != 0 || count != 0 || tracked {
+		t.Errorf("Expire(0) left %v keys, %d slots", ms.Stats()["totalStored"], count)
+	}
+
+	// 时间轮按新的过期时间清理
+	ms.Set("short", 1, time.Hour)
+	ms.Expire("short", 2*time.Second)
+	for i := 0; ms.Stats()["totalStored"] != 0; i++ {
+		if i == 1000 {
+			t.Fatalf("rescheduled key was not collected")
+		}
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCache_SlidingExpiration(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	c := NewCache[string, int](WithClock(clock), WithSlidingExpiration())
+	defer c.Close()
+
+	c.Set("session", 1, 10*time.Second)
+	c.Set("fixed", 2, NoExpiration)
+	for i := 0; i < 5; i++ {
+		clock.Set(clock.Now().Add(6 * time.Second))
+		if _, ok := c.Get("session"); !ok {
+			t.Fatalf("session expired after read %d", i)
+		}
+		if ttl, _ := c.TTL("session"); ttl != 10*time.Second {
+			t.Fatalf("TTL after read = %v, want 10s", ttl)
+		}
+	}
+	if ttl, _ := c.TTL("fixed"); ttl != NoExpiration {
+		t.Errorf("persistent key TTL = %v", ttl)
+	}
+
+	// Expire 同时修改顺延的时长，KeepTTL 保留它
+	c.Expire("session", 3*time.Second)
+	c.Set("session", 3, KeepTTL)
+	clock.Set(clock.Now().Add(2 * time.Second))
+	c.GetOrSet("session", 0, time.Minute)
+	if ttl, _ := c.TTL("session"); ttl != 3*time.Second {
+		t.Errorf("TTL after Expire = %v, want 3s", ttl)
+	}
+	if count, tracked := wheelSlots(c.wheel, "session"); count != 1 || !tracked {
+		t.Errorf("sliding key: %d slots, tracked %v", count, tracked)
+	}
+
+	clock.Set(clock.Now().Add(3 * time.Second))
+	if _, ok := c.Get("session"); ok {
+		t.Errorf("session should expire without reads")
+	}
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// loadCall 进行中的一次加载，完成后关闭 done
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// loadFailure 缓存的加载错误
+type loadFailure struct {
+	err   error
+	until time.Time
+}
+
+// loadGroup 合并同一个键的并发加载，并缓存加载错误
+type loadGroup[K comparable, V any] struct {
+	ttl          time.Duration // 加载结果的过期时间
+	refreshAfter time.Duration // 加载结果的软过期时间
+	negativeTTL  time.Duration // 加载错误的缓存时间
+
+	mu       sync.Mutex
+	calls    map[K]*loadCall[V]
+	failures map[K]loadFailure
+	sweepAt  int // failures 达到该数量时清理已过期的错误
+}
+
+// join 返回键正在进行的加载，没有时创建一个，leader 为 true 表示调用方需要执行加载
+func (g *loadGroup[K, V]) join(key K) (call *loadCall[V], leader bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+	if g.calls == nil {
+		g.calls = make(map[K]*loadCall[V])
+	}
+	call = &loadCall[V]{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+// finish 结束加载，按需缓存错误后唤醒等待方
+func (g *loadGroup[K, V]) finish(key K, call *loadCall[V], now time.Time) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	if call.err != nil && g.negativeTTL > 0 {
+		if g.failures == nil {
+			g.failures = make(map[K]loadFailure)
+		}
+		if len(g.failures) >= g.sweepAt {
+			for k, failure := range g.failures {
+				if !now.Before(failure.until) {
+					delete(g.failures, k)
+				}
+			}
+			g.sweepAt = max(64, len(g.failures)*2)
+		}
+		g.failures[key] = loadFailure{err: call.err, until: now.Add(g.negativeTTL)}
+	}
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// failure 返回键在缓存期内的加载错误
+func (g *loadGroup[K, V]) failure(key K, now time.Time) (error, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	failure, ok := g.failures[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(failure.until) {
+		delete(g.failures, key)
+		return nil, false
+	}
+	return failure.err, true
+}
+
+// forget 清除键缓存的加载错误
+func (g *loadGroup[K, V]) forget(key K) {
+	g.mu.Lock()
+	delete(g.failures, key)
+	g.mu.Unlock()
+}
+
+// GetOrLoad 键存在且未过期时直接返回，否则调用 loader 加载，结果按 WithLoadTTL 缓存。
+// 同一个键的并发未命中只调用一次 loader，loader 收到的 ctx 不随调用方取消，调用方的 ctx 取消时只是不再等待。
+// 设置 WithNegativeTTL 后加载错误会被缓存，期间直接返回该错误；
+// 设置 WithRefreshAfter 后，加载结果超过软过期时间时返回旧值并在后台刷新，刷新失败时继续返回旧值直到硬过期
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (V, error) {
+	now := c.clock.Now()
+	if entry, ok := c.lookup(key, now); ok {
+		if entry.stale(now) {
+			if _, failed := c.loads.failure(key, now); !failed {
+				if call, leader := c.loads.join(key); leader {
+					go c.load(context.WithoutCancel(ctx), key, loader, call, entry)
+				}
+			}
+		}
+		return entry.value, nil
+	}
+
+	var zero V
+	if err, ok := c.loads.failure(key, now); ok {
+		return zero, err
+	}
+	call, leader := c.loads.join(key)
+	if leader {
+		go c.load(context.WithoutCancel(ctx), key, loader, call, nil)
+	}
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// load 调用 loader 并写入结果，stale 为需要刷新的旧条目，首次加载时为 nil
+func (c *Cache[K, V]) load(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error), call *loadCall[V], stale *cacheEntry[V]) {
+	defer func() {
+		c.loads.finish(key, call, c.clock.Now())
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("store: loader panic: %v", r)
+		}
+	}()
+
+	shard := c.getShard(key)
+	generation := c.beginLoad(shard, key)
+	defer c.endLoad(shard, key, generation)
+
+	value, err := loader(ctx, key)
+	if err != nil {
+		call.err = err
+		return
+	}
+	call.value = value
+
+	now := c.clock.Now()
+	entry := c.newEntry(value, c.expireAt(c.loads.ttl))
+	if c.loads.refreshAfter > 0 {
+		entry.refreshAt = now.Add(c.loads.refreshAfter)
+	}
+	c.notify(c.shrink(shard, c.storeLoaded(shard, key, entry, stale, generation, now)))
+}
+
+// beginLoad 记录加载开始时键的代数
+func (c *Cache[K, V]) beginLoad(shard *cacheShard[K, V], key K) uint64 {
+	shard.Lock()
+	defer shard.Unlock()
+
+	if shard.loading == nil {
+		shard.loading = make(map[K]uint64)
+	}
+	generation := c.versions.Add(1)
+	shard.loading[key] = generation
+	return generation
+}
+
+// endLoad 加载结束后清除记录的代数
+func (c *Cache[K, V]) endLoad(shard *cacheShard[K, V], key K, generation uint64) {
+	shard.Lock()
+	defer shard.Unlock()
+
+	if shard.loading[key] == generation {
+		delete(shard.loading, key)
+	}
+}
+
+// supersede 键被写入或删除时使进行中的加载失效，调用方需持有分片写锁
+func (c *Cache[K, V]) supersede(shard *cacheShard[K, V], key K) {
+	if len(shard.loading) > 0 {
+		delete(shard.loading, key)
+	}
+}
+
+// storeLoaded 写入加载结果。加载期间键被写入或删除时代数已失效，
+// 当前条目已不是 stale 且未过期时说明加载开始前键已被重新写入，两种情况都保留当前状态
+func (c *Cache[K, V]) storeLoaded(shard *cacheShard[K, V], key K, entry *cacheEntry[V], stale *cacheEntry[V], generation uint64, now time.Time) []eviction[K, V] {
+	shard.Lock()
+	defer shard.Unlock()
+
+	if shard.loading[key] != generation {
+		return nil
+	}
+	if current, ok := shard.items[key]; ok && (stale == nil || current.version != stale.version) && !current.expired(now) {
+		return nil
+	}
+	return c.store(shard, key, entry, nil)
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// waitFor 轮询直到条件成立，后台加载没有可等待的信号
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for i := 0; !condition(); i++ {
+		if i == 1000 {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCache_GetOrLoad_Singleflight(t *testing.T) {
+	c := NewCache[string, int]()
+	defer c.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return len(key), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 20)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad(context.Background(), "hello", loader)
+		}()
+	}
+	waitFor(t, "loader call", func() bool { return calls.Load() == 1 })
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("loader called %d times, want 1", calls.Load())
+	}
+	for i, result := range results {
+		if result != 5 {
+			t.Errorf("results[%d] = %d, want 5", i, result)
+		}
+	}
+	if value, ok := c.Get("hello"); !ok || value != 5 {
+		t.Errorf("Get = %d, %v", value, ok)
+	}
+}
+
+func TestCache_GetOrLoad_Cancel(t *testing.T) {
+	c := NewCache[string, string]()
+	defer c.Close()
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (string, error) {
+		<-release
+		return "loaded", ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetOrLoad(ctx, "key", loader); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetOrLoad with canceled ctx: %v", err)
+	}
+
+	// 调用方取消不影响进行中的加载
+	close(release)
+	if value, err := c.GetOrLoad(context.Background(), "key", loader); err != nil || value != "loaded" {
+		t.Errorf("GetOrLoad = %q, %v", value, err)
+	}
+
+	_, err := c.GetOrLoad(context.Background(), "panic", func(ctx context.Context, key string) (string, error) {
+		panic("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("loader panic: %v", err)
+	}
+}
+
+// 测试加载期间键被删除时丢弃加载结果，避免写回已删除的值
+func TestCache_GetOrLoad_DeleteDuringLoad(t *testing.T) {
+	c := NewCache[string, int]()
+	defer c.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		if calls.Add(1) == 1 {
+			<-release
+		}
+		return int(calls.Load()), nil
+	}
+
+	done := make(chan int)
+	go func() {
+		value, _ := c.GetOrLoad(context.Background(), "key", loader)
+		done <- value
+	}()
+	waitFor(t, "loader call", func() bool { return calls.Load() == 1 })
+	c.Delete("key")
+	close(release)
+	if value := <-done; value != 1 {
+		t.Errorf("GetOrLoad = %d, want 1 for the caller that started the load", value)
+	}
+	if value, ok := c.Get("key"); ok {
+		t.Errorf("Get = %d after delete during load, want miss", value)
+	}
+	if value, err := c.GetOrLoad(context.Background(), "key", loader); err != nil || value != 2 {
+		t.Errorf("GetOrLoad = %d, %v; want a new load", value, err)
+	}
+}
+
+func TestCache_GetOrLoad_NegativeCache(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	c := NewCache[string, int](WithClock(clock), WithNegativeTTL(5*time.Second))
+	defer c.Close()
+
+	errNotFound := errors.New("not found")
+	var calls atomic.Int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errNotFound
+		}
+		return 7, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "key", loader); !errors.Is(err, errNotFound) {
+			t.Fatalf("GetOrLoad #%d: %v", i, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("loader called %d times during negative TTL, want 1", calls.Load())
+	}
+
+	clock.Set(clock.Now().Add(5 * time.Second))
+	if value, err := c.GetOrLoad(context.Background(), "key", loader); err != nil || value != 7 {
+		t.Errorf("GetOrLoad after negative TTL = %d, %v", value, err)
+	}
+
+	// Delete 清除缓存的错误
+	calls.Store(0)
+	c.Delete("key")
+	c.GetOrLoad(context.Background(), "key", loader)
+	c.Delete("key")
+	if value, err := c.GetOrLoad(context.Background(), "key", loader); err != nil || value != 7 {
+		t.Errorf("GetOrLoad after Delete = %d, %v", value, err)
+	}
+}
+
+func TestCache_GetOrLoad_StaleWhileRevalidate(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	c := NewCache[string, int](WithClock(clock), WithLoadTTL(10*time.Second), WithRefreshAfter(2*time.Second),
+		WithNegativeTTL(time.Second))
+	defer c.Close()
+
+	var version atomic.Int32
+	var fail atomic.Bool
+	release := make(chan struct{}, 1)
+	loader := func(ctx context.Context, key string) (int, error) {
+		if version.Load() > 0 {
+			<-release
+		}
+		if fail.Load() {
+			return 0, errors.New("unavailable")
+		}
+		return int(version.Add(1)), nil
+	}
+	get := func() int {
+		t.Helper()
+		value, err := c.GetOrLoad(context.Background(), "key", loader)
+		if err != nil {
+			t.Fatalf("GetOrLoad: %v", err)
+		}
+		return value
+	}
+
+	if value := get(); value != 1 {
+		t.Fatalf("initial load = %d", value)
+	}
+	clock.Set(clock.Now().Add(3 * time.Second))
+	// 超过软过期时间后立即返回旧值，刷新在后台进行
+	if value := get(); value != 1 {
+		t.Errorf("stale read = %d, want 1", value)
+	}
+	if value := get(); value != 1 {
+		t.Errorf("stale read during refresh = %d, want 1", value)
+	}
+	release <- struct{}{}
+	waitFor(t, "refresh", func() bool { value, _ := c.Get("key"); return value == 2 })
+
+	// 刷新失败时继续返回旧值，直到硬过期
+	fail.Store(true)
+	clock.Set(clock.Now().Add(3 * time.Second))
+	release <- struct{}{}
+	if value := get(); value != 2 {
+		t.Errorf("stale read = %d, want 2", value)
+	}
+	waitFor(t, "failed refresh", func() bool { return len(release) == 0 })
+	time.Sleep(10 * time.Millisecond)
+	if value := get(); value != 2 {
+		t.Errorf("stale read after failed refresh = %d, want 2", value)
+	}
+
+	clock.Set(clock.Now().Add(10 * time.Second))
+	release <- struct{}{}
+	if _, err := c.GetOrLoad(context.Background(), "key", loader); err == nil {
+		t.Errorf("expected load error after hard TTL")
+	}
+}
+
+func TestMemoryStore_GetOrLoad(t *testing.T) {
+	ms := NewMemoryStore(4, 10, time.Second, WithLoadTTL(time.Minute))
+	defer ms.Close()
+
+	value, err := ms.GetOrLoad(context.Background(), "user:1", func(ctx context.Context, key string) (any, error) {
+		return strings.TrimPrefix(key, "user:"), nil
+	})
+	if err != nil || value != "1" {
+		t.Fatalf("GetOrLoad = %v, %v", value, err)
+	}
+	if value, seconds, ok := ms.Get("user:1", false); !ok || value != "1" || seconds <= 0 {
+		t.Errorf("Get = %v, %d, %v", value, seconds, ok)
+	}
+}
@@ -1,157 +1,98 @@
 package store
 
 import (
-	"hash/fnv"
-	"sync"
+	"context"
 	"time"
 )
 
-// shard 用于分片存储数据，每个分片维护独立锁和结构
-type shard struct {
-	sync.RWMutex
-	data      map[string]any       // 数据存储
-	expireMap map[string]time.Time // 存储每个键的过期时间
-	numStored int                  // 当前存储数量
-}
-
-// MemoryStore 是主存储结构，包含多个分片
+// MemoryStore 是值类型为 any 的字符串键存储，基于 Cache 实现，新代码建议直接使用类型安全的 Cache
 type MemoryStore struct {
-	shards     []shard    // 数据分片
-	shardCount int        // 分片数
-	timeWheel  *TimeWheel // 时间轮实例
+	cache      *Cache[string, any]
+	shardCount int // 分片数
 }
 
 // NewMemoryStore 创建一个新的 MemoryStore
-func NewMemoryStore(shardCount int, slotCount int, tickInterval time.Duration) *MemoryStore {
-	shards := make([]shard, shardCount)
-	for i := 0; i < shardCount; i++ {
-		shards[i] = shard{
-			data:      make(map[string]any),
-			expireMap: make(map[string]time.Time),
-		}
-	}
-	// 创建并启动时间轮
-	timeWheel := newTimeWheel(slotCount, tickInterval, nil) // 这里传递 nil，稍后再设置 MemoryStore
-	ms := &MemoryStore{
-		shards:     shards,
+func NewMemoryStore(shardCount int, slotCount int, tickInterval time.Duration, opts ...Option) *MemoryStore {
+	opts = append([]Option{WithShards(shardCount), WithTimeWheel(slotCount, tickInterval)}, opts...)
+	return &MemoryStore{
+		cache:      NewCache[string, any](opts...),
 		shardCount: shardCount,
-		timeWheel:  timeWheel,
 	}
-
-	// 设置 timeWheel 的 store 引用
-	ms.timeWheel.store = ms
-	// 启动时间轮
-	go ms.timeWheel.Start()
-
-	return ms
-}
-
-// getShard 获取对应分片
-func (ms *MemoryStore) getShard(key string) *shard {
-	hash := fnv.New32a()
-	hash.Write([]byte(key))
-	idx := int(hash.Sum32()) % ms.shardCount
-	return &ms.shards[idx]
 }
 
-// Set 设置键值对，并处理过期时间
+// Set 设置键值对，ttl 为 -1 时永不过期
 func (ms *MemoryStore) Set(key string, value any, ttl time.Duration) {
-	shard := ms.getShard(key)
-	shard.Lock()
-	defer shard.Unlock()
-
-	// 设置值并记录过期时间
 	if ttl == -1 {
-		// 删除对应的过期key
-		delete(shard.expireMap, key)
-		ms.timeWheel.Remove(key)
-	} else {
-		expirationTime := time.Now().Add(ttl)
-		shard.expireMap[key] = expirationTime // 记录过期时间
-		// 通过时间轮添加键
-		ms.timeWheel.Add(key, expirationTime)
+		ms.cache.setUntil(key, value, time.Time{})
+		return
 	}
-
-	shard.data[key] = value
-	shard.numStored++
+	ms.cache.setUntil(key, value, ms.cache.clock.Now().Add(ttl))
 }
 
-// Get 获取键值对，并检查是否过期
+// Get 获取键值对和剩余秒数，永不过期的键剩余秒数为 -1；clear 为 true 时读取后删除
 func (ms *MemoryStore) Get(key string, clear bool) (any, int64, bool) {
-	shard := ms.getShard(key)
-	shard.RLock()
-	defer shard.RUnlock()
-
-	// 检查键是否存在
-	value, exists := shard.data[key]
+	get := ms.cache.get
+	if clear {
+		// 读取与删除在同一次加锁中完成，并发读取并清除时只有一个调用方能取得值
+		get = ms.cache.getAndDelete
+	}
+	value, expireAt, exists := get(key)
 	if !exists {
 		return nil, 0, false
 	}
 
-	// 检查过期时间
-	nowTime := time.Now()
-	expireAt, ok := shard.expireMap[key]
-	if !ok || expireAt.Before(nowTime) {
-		return nil, 0, false // 如果键过期，则返回 nil
+	if expireAt.IsZero() {
+		return value, -1, true
 	}
+	seconds := int64(expireAt.Sub(ms.cache.clock.Now()).Seconds())
+	return value, seconds, true
+}
 
-	// 清除指定 key
-	if clear {
-		ms.collectSpecifiedKey(shard, key)
-	}
+// TTL 返回键精确的剩余存活时间，永不过期的键返回 -1，键不存在或已过期时返回 false
+func (ms *MemoryStore) TTL(key string) (time.Duration, bool) {
+	return ms.cache.TTL(key)
+}
 
-	seconds := int64(expireAt.Sub(nowTime).Seconds())
-	return value, seconds, true
+// Expire 修改键的过期时间为 ttl 之后，不改写值，ttl <= 0 时立即删除键。键不存在或已过期时返回 false
+func (ms *MemoryStore) Expire(key string, ttl time.Duration) bool {
+	return ms.cache.Expire(key, ttl)
+}
+
+// ExpireAt 将键的过期时间设为 deadline，deadline 不晚于当前时间时立即删除键。键不存在或已过期时返回 false
+func (ms *MemoryStore) ExpireAt(key string, deadline time.Time) bool {
+	return ms.cache.ExpireAt(key, deadline)
+}
+
+// Persist 移除键的过期时间，返回键是否存在且原本设置了过期时间
+func (ms *MemoryStore) Persist(key string) bool {
+	return ms.cache.Persist(key)
 }
 
-// collectSpecifiedKey 清除指定 key
-func (ms *MemoryStore) collectSpecifiedKey(shard *shard, key string) {
-	delete(shard.data, key)
-	delete(shard.expireMap, key)
-	shard.numStored--
+// GetOrLoad 读取键，不存在时调用 loader 加载，见 Cache.GetOrLoad
+func (ms *MemoryStore) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context, key string) (any, error)) (any, error) {
+	return ms.cache.GetOrLoad(ctx, key, loader)
 }
 
 // Delete 删除键
 func (ms *MemoryStore) Delete(key string) {
-	shard := ms.getShard(key)
-	shard.Lock()
-	defer shard.Unlock()
-
-	ms.collectSpecifiedKey(shard, key)
+	ms.cache.Delete(key)
 }
 
-// IsExpired 检查指定键是否已过期
+// IsExpired 检查指定键是否已过期，键不存在时视为已过期
 func (ms *MemoryStore) IsExpired(key string) bool {
-	shard := ms.getShard(key)
-	shard.RLock()
-	defer shard.RUnlock()
-
-	// 检查键是否存在
-	expireAt, exists := shard.expireMap[key]
-	if !exists {
-		return true // 键不存在，则视为已过期
-	}
-
-	// 检查是否过期
-	return expireAt.Before(time.Now())
+	_, _, exists := ms.cache.get(key)
+	return !exists
 }
 
 // Stats 返回当前统计信息
 func (ms *MemoryStore) Stats() map[string]any {
-	totalStored := 0
-	for i := range ms.shards {
-		totalStored += ms.shards[i].numStored
-	}
 	return map[string]any{
-		"totalStored": totalStored,
+		"totalStored": ms.cache.Len(),
 		"shardCount":  ms.shardCount,
 	}
 }
 
 // Close 停止时间轮并清理资源
 func (ms *MemoryStore) Close() {
-	// 停止时间轮
-	if ms.timeWheel != nil {
-		ms.timeWheel.Stop()
-	}
+	ms.cache.Close()
 }
@@ -2,13 +2,18 @@ package store
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
 )
 
 // 测试 MemoryStore 的 Set 和 Get 方法
 func TestMemoryStore_Set_Get(t *testing.T) {
-	ms := NewMemoryStore(4, 10, time.Second) // 创建一个带有 4 个分片的 MemoryStore
+	clock := timeutil.NewFakeClock(time.Now())
+	ms := NewMemoryStore(4, 10, time.Second, WithClock(clock)) // 创建一个带有 4 个分片的 MemoryStore
 
 	// 设置一个有效的键值对，TTL 设置为 2 秒
 	ms.Set("key1", "value1", 2*time.Second)
@@ -25,7 +30,7 @@ func TestMemoryStore_Set_Get(t *testing.T) {
 	fmt.Println("---ttl", ttl)
 
 	// 等待键过期
-	time.Sleep(3 * time.Second)
+	clock.Advance(3 * time.Second)
 
 	// 尝试获取过期的键
 	value, ttl, exists = ms.Get("key1", false)
@@ -54,21 +59,57 @@ func TestMemoryStore_Delete(t *testing.T) {
 	}
 }
 
+// 测试并发读取并清除同一个键时只有一个调用方取得值
+func TestMemoryStore_GetClear(t *testing.T) {
+	ms := NewMemoryStore(4, 10, time.Second)
+	defer ms.Close()
+
+	for round := 0; round < 100; round++ {
+		ms.Set("token", round, -1)
+		var wg sync.WaitGroup
+		var got atomic.Int32
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if value, _, ok := ms.Get("token", true); ok && value == round {
+					got.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if got.Load() != 1 {
+			t.Fatalf("round %d: %d callers received the value, want 1", round, got.Load())
+		}
+	}
+
+	c := NewCache[string, int]()
+	defer c.Close()
+	c.Set("key", 1, NoExpiration)
+	if value, ok := c.GetAndDelete("key"); !ok || value != 1 || c.Len() != 0 {
+		t.Errorf("GetAndDelete = %d, %v; Len %d", value, ok, c.Len())
+	}
+	if _, ok := c.GetAndDelete("key"); ok {
+		t.Errorf("GetAndDelete on missing key reported ok")
+	}
+}
+
 // 测试 MemoryStore 的 IsExpired 方法
 func TestMemoryStore_IsExpired(t *testing.T) {
-	ms := NewMemoryStore(4, 10, time.Second)
+	clock := timeutil.NewFakeClock(time.Now())
+	ms := NewMemoryStore(4, 10, time.Second, WithClock(clock))
 
 	// 设置一个有效的键值对，TTL 设置为 2 秒
 	ms.Set("key1", "value1", 2*time.Second)
 
 	// 等待一秒，键应该未过期
-	time.Sleep(1 * time.Second)
+	clock.Advance(1 * time.Second)
 	if ms.IsExpired("key1") {
 		t.Errorf("Expected key1 to not be expired, but it is expired")
 	}
 
 	// 等待超过 TTL，键应该过期
-	time.Sleep(2 * time.Second)
+	clock.Advance(2 * time.Second)
 	if !ms.IsExpired("key1") {
 		t.Errorf("Expected key1 to be expired, but it is not expired")
 	}
@@ -88,3 +129,24 @@ func TestMemoryStore_Stats(t *testing.T) {
 		t.Errorf("Expected totalStored to be 2, but got %v", stats["totalStored"])
 	}
 }
+
+// 测试时间轮使用注入的时钟清理过期键
+func TestMemoryStore_TimeWheelWithFakeClock(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Now())
+	ms := NewMemoryStore(4, 10, time.Second, WithClock(clock))
+	defer ms.Close()
+
+	ms.Set("key1", "value1", 2*time.Second)
+
+	// Ticker 会丢弃来不及接收的触发，逐秒推进直到时间轮转到 key1 所在槽位
+	for i := 0; ms.Stats()["totalStored"] != 0; i++ {
+		if i == 1000 {
+			t.Fatalf("expected key1 to be collected by time wheel, stats %v", ms.Stats())
+		}
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, exists := ms.Get("key1", false); exists {
+		t.Errorf("expected key1 to be expired")
+	}
+}
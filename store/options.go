@@ -0,0 +1,150 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
+)
+
+// options MemoryStore 和 Cache 共用的配置
+type options struct {
+	shardCount   int
+	slotCount    int
+	tickInterval time.Duration
+	clock        timeutil.Clock
+	hasher       any // func(K) uint64，由 NewCache 按键类型断言
+	maxEntries   int
+	maxCost      int64
+	cost         any // func(V) int64，由 NewCache 按值类型断言
+	policy       EvictionPolicy
+	onEvict      any // func(K, V, EvictionReason)，由 NewCache 按键值类型断言
+	loadTTL      time.Duration
+	refreshAfter time.Duration
+	negativeTTL  time.Duration
+	sliding      bool
+}
+
+// Option MemoryStore 和 Cache 的可选配置
+type Option func(o *options)
+
+func defaultOptions() options {
+	return options{
+		shardCount:   16,
+		slotCount:    60,
+		tickInterval: time.Second,
+		clock:        timeutil.RealClock{},
+	}
+}
+
+// WithClock 设置时钟，默认系统时钟，测试时可注入 timeutil.FakeClock
+func WithClock(clock timeutil.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithShards 设置分片数，默认 16
+func WithShards(shardCount int) Option {
+	return func(o *options) {
+		o.shardCount = shardCount
+	}
+}
+
+// WithTimeWheel 设置时间轮的槽数和每个槽位的时间间隔，默认 60 个槽、每槽 1 秒。
+// 过期键最晚在过期后一个时间间隔内被清理，超过一圈的过期时间按圈数计数
+func WithTimeWheel(slotCount int, tickInterval time.Duration) Option {
+	return func(o *options) {
+		o.slotCount = slotCount
+		o.tickInterval = tickInterval
+	}
+}
+
+// WithHasher 设置键的哈希函数，用于选择分片，K 必须与 Cache 的键类型一致。
+// 默认对字符串和整数直接哈希，其他类型按 fmt.Sprint 的结果哈希
+func WithHasher[K comparable](hasher func(key K) uint64) Option {
+	return func(o *options) {
+		o.hasher = hasher
+	}
+}
+
+// WithMaxEntries 设置所有分片的条目数上限，超出时按淘汰策略淘汰
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithMaxCost 设置所有分片的总成本上限（如字节数），每个条目的成本由 WithCost 计算，未设置时每个条目为 1
+func WithMaxCost(maxCost int64) Option {
+	return func(o *options) {
+		o.maxCost = maxCost
+	}
+}
+
+// WithCost 设置条目成本的计算函数，V 必须与 Cache 的值类型一致
+func WithCost[V any](cost func(value V) int64) Option {
+	return func(o *options) {
+		o.cost = cost
+	}
+}
+
+// WithEvictionPolicy 设置淘汰策略，默认 EvictLRU，仅在设置了容量上限时生效
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithEvictionCallback 设置条目被移除时的回调，K、V 必须与 Cache 的键值类型一致。
+// 回调在分片锁之外同步调用，可以读写缓存
+func WithEvictionCallback[K comparable, V any](onEvict func(key K, value V, reason EvictionReason)) Option {
+	return func(o *options) {
+		o.onEvict = onEvict
+	}
+}
+
+// WithLoadTTL 设置 GetOrLoad 加载结果的过期时间，到期后由时间轮清理，<= 0 时永不过期（默认）
+func WithLoadTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.loadTTL = ttl
+	}
+}
+
+// WithRefreshAfter 设置 GetOrLoad 加载结果的软过期时间，应小于 WithLoadTTL。
+// 超过软过期时间后 GetOrLoad 仍返回旧值，同时在后台重新加载，<= 0 时不刷新（默认）
+func WithRefreshAfter(refreshAfter time.Duration) Option {
+	return func(o *options) {
+		o.refreshAfter = refreshAfter
+	}
+}
+
+// WithNegativeTTL 设置 GetOrLoad 缓存加载错误的时间，期间同一个键直接返回该错误而不再调用 loader，<= 0 时不缓存（默认）
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithSlidingExpiration 开启滑动过期，读取未过期的键时将过期时间顺延为读取时刻加上最近一次设置的 ttl。
+// 读取需要获取分片写锁，读多的场景会降低并发度
+func WithSlidingExpiration() Option {
+	return func(o *options) {
+		o.sliding = true
+	}
+}
+
+func (o *options) validate() {
+	if o.shardCount <= 0 {
+		panic(fmt.Sprintf("store: shard count %d must be positive", o.shardCount))
+	}
+	if o.slotCount <= 0 || o.tickInterval <= 0 {
+		panic(fmt.Sprintf("store: time wheel %d slots of %v must be positive", o.slotCount, o.tickInterval))
+	}
+	if o.maxEntries < 0 || o.maxCost < 0 {
+		panic(fmt.Sprintf("store: max entries %d and max cost %d must not be negative", o.maxEntries, o.maxCost))
+	}
+	if o.clock == nil {
+		o.clock = timeutil.RealClock{}
+	}
+}
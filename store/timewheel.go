@@ -3,81 +3,119 @@ package store
 import (
 	"sync"
 	"time"
+
+	"github.com/dhlanshan/lotus/timeutil"
 )
 
-// timeSlot 存储过期键的时间轮槽结构
-type timeSlot struct {
-	sync.Mutex
-	keys map[string]struct{} // 存储键集合
+// wheelPosition 键在时间轮中的位置
+type wheelPosition struct {
+	slot   int // 槽位
+	rounds int // 还需转过的圈数，过期时间超过一圈时使用
+}
+
+// wheelShard 时间轮的一个分片，与缓存分片一一对应，写入时只与同一分片的 tick 竞争锁
+type wheelShard[K comparable] struct {
+	mu          sync.Mutex
+	slots       []map[K]struct{}    // 槽数组，每个槽存储键集合
+	positions   map[K]wheelPosition // 键所在的槽位，保证每个键只在一个槽位中
+	currentSlot int                 // 当前槽位置
 }
 
-// TimeWheel 是时间轮的核心结构
-type TimeWheel struct {
-	slots        []*timeSlot    // 槽数组
-	slotCount    int            // 槽数
-	tickInterval time.Duration  // 每个槽位的时间间隔
-	currentSlot  int            // 当前槽位置
-	ticker       *time.Ticker   // 定时器
-	stopChan     chan struct{}  // 停止信号通道
-	store        *MemoryStore   // 引用 MemoryStore
+// TimeWheel 是时间轮的核心结构，每个 tick 依次处理各分片的一个槽位，并将到期的键交给 expire 回调
+type TimeWheel[K comparable] struct {
+	shards       []wheelShard[K]
+	hasher       func(key K) uint64 // 与缓存相同的哈希，使键落在对应缓存分片的时间轮分片
+	slotCount    int                // 槽数
+	tickInterval time.Duration      // 每个槽位的时间间隔
+	ticker       timeutil.Ticker    // 定时器
+	clock        timeutil.Clock     // 时钟
+	expire       func(keys []K)     // 到期回调，在时间轮锁之外调用
+	stopChan     chan struct{}      // 停止信号通道
+	stopOnce     sync.Once
 	wg           sync.WaitGroup // 等待 goroutine 完成
 }
 
-// NewTimeWheel 创建一个新的时间轮
-func newTimeWheel(slotCount int, tickInterval time.Duration, store *MemoryStore) *TimeWheel {
-	slots := make([]*timeSlot, slotCount)
-	for i := 0; i < slotCount; i++ {
-		slots[i] = &timeSlot{keys: make(map[string]struct{})}
+// newTimeWheel 创建一个新的时间轮，键按 hasher 分布到 shardCount 个分片
+func newTimeWheel[K comparable](shardCount, slotCount int, tickInterval time.Duration, clock timeutil.Clock, hasher func(key K) uint64, expire func(keys []K)) *TimeWheel[K] {
+	if clock == nil {
+		clock = timeutil.RealClock{}
+	}
+
+	shards := make([]wheelShard[K], shardCount)
+	for i := range shards {
+		shards[i].slots = make([]map[K]struct{}, slotCount)
+		for j := range shards[i].slots {
+			shards[i].slots[j] = make(map[K]struct{})
+		}
+		shards[i].positions = make(map[K]wheelPosition)
 	}
 
-	return &TimeWheel{
-		slots:        slots,
+	return &TimeWheel[K]{
+		shards:       shards,
+		hasher:       hasher,
 		slotCount:    slotCount,
 		tickInterval: tickInterval,
-		currentSlot:  0,
-		ticker:       time.NewTicker(tickInterval),
+		ticker:       clock.NewTicker(tickInterval),
+		clock:        clock,
+		expire:       expire,
 		stopChan:     make(chan struct{}),
-		store:        store,
 	}
 }
 
-// Add 键加入到时间轮中
-func (tw *TimeWheel) Add(key string, expireAt time.Time) {
-	slotIndex := tw.calculateSlot(expireAt)
+func (tw *TimeWheel[K]) getShard(key K) *wheelShard[K] {
+	return &tw.shards[tw.hasher(key)%uint64(len(tw.shards))]
+}
 
-	slot := tw.slots[slotIndex]
-	slot.Lock()
-	defer slot.Unlock()
+// Add 键加入到时间轮中，键已存在时移动到新的槽位
+func (tw *TimeWheel[K]) Add(key K, expireAt time.Time) {
+	shard := tw.getShard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	// 将键添加到对应槽位
-	slot.keys[key] = struct{}{}
+	shard.remove(key)
+	position := tw.calculatePosition(shard, expireAt)
+	shard.slots[position.slot][key] = struct{}{}
+	shard.positions[key] = position
 }
 
 // Remove 移除键
-func (tw *TimeWheel) Remove(key string) {
-	for _, slot := range tw.slots {
-		slot.Lock()
-		delete(slot.keys, key)
-		slot.Unlock()
+func (tw *TimeWheel[K]) Remove(key K) {
+	shard := tw.getShard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.remove(key)
+}
+
+func (s *wheelShard[K]) remove(key K) {
+	if position, ok := s.positions[key]; ok {
+		delete(s.slots[position.slot], key)
+		delete(s.positions, key)
 	}
 }
 
-// calculateSlot 计算键所属的槽位
-func (tw *TimeWheel) calculateSlot(expireAt time.Time) int {
-	duration := expireAt.Sub(time.Now())
-	slotOffset := int(duration / tw.tickInterval)
-	return (tw.currentSlot + slotOffset) % tw.slotCount
+// calculatePosition 计算键在分片中所属的槽位。下一次 tick 处理当前槽位，
+// 因此向上取整的第 n 个槽位在 n+1 次 tick 后处理，不会早于过期时间
+func (tw *TimeWheel[K]) calculatePosition(shard *wheelShard[K], expireAt time.Time) wheelPosition {
+	var ticks int64
+	if duration := expireAt.Sub(tw.clock.Now()); duration > 0 {
+		ticks = int64((duration + tw.tickInterval - 1) / tw.tickInterval)
+	}
+	return wheelPosition{
+		slot:   int((int64(shard.currentSlot) + ticks) % int64(tw.slotCount)),
+		rounds: int(ticks / int64(tw.slotCount)),
+	}
 }
 
 // Start 启动时间轮
-func (tw *TimeWheel) Start() {
+func (tw *TimeWheel[K]) Start() {
 	tw.wg.Add(1) // 增加一个 goroutine 等待
 	go func() {
 		defer tw.wg.Done()
 		for {
 			select {
-			case <-tw.ticker.C:
-				tw.tick(tw.store) // 每次 tick
+			case <-tw.ticker.Chan():
+				tw.tick() // 每次 tick
 			case <-tw.stopChan:
 				tw.ticker.Stop()
 				return
@@ -86,29 +124,44 @@ func (tw *TimeWheel) Start() {
 	}()
 }
 
-// tick 时间轮每个槽位更新时处理过期的键
-func (tw *TimeWheel) tick(store *MemoryStore) {
-	// 获取当前槽位
-	currentSlot := tw.slots[tw.currentSlot]
-
-	currentSlot.Lock()
-	keys := make([]string, 0, len(currentSlot.keys))
-	for key := range currentSlot.keys {
-		keys = append(keys, key)
+// tick 时间轮每个槽位更新时处理过期的键，各分片依次加锁
+func (tw *TimeWheel[K]) tick() {
+	var expired []K
+	for i := range tw.shards {
+		expired = tw.shards[i].tick(expired, tw.slotCount)
 	}
-	currentSlot.keys = make(map[string]struct{}) // 清空当前槽位
-	currentSlot.Unlock()
 
-	// 处理过期键
-	for _, key := range keys {
-		store.Delete(key) // 删除存储中的键
+	if len(expired) > 0 && tw.expire != nil {
+		tw.expire(expired)
 	}
+}
 
+// tick 处理分片的当前槽位，将到期的键追加到 expired
+func (s *wheelShard[K]) tick(expired []K, slotCount int) []K {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot := s.slots[s.currentSlot]
+	for key := range slot {
+		position := s.positions[key]
+		if position.rounds > 0 {
+			position.rounds--
+			s.positions[key] = position
+			continue
+		}
+		expired = append(expired, key)
+		delete(slot, key)
+		delete(s.positions, key)
+	}
 	// 移动到下一个槽位
-	tw.currentSlot = (tw.currentSlot + 1) % tw.slotCount
+	s.currentSlot = (s.currentSlot + 1) % slotCount
+	return expired
 }
 
-// Stop 停止时间轮
-func (tw *TimeWheel) Stop() {
-	close(tw.stopChan)
+// Stop 停止时间轮，返回后不会再触发到期回调
+func (tw *TimeWheel[K]) Stop() {
+	tw.stopOnce.Do(func() {
+		close(tw.stopChan)
+	})
+	tw.wg.Wait()
 }
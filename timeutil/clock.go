@@ -0,0 +1,159 @@
+package timeutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟接口，依赖当前时间的组件通过它获取时间，便于测试中替换
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 定时器接口，对应 time.Ticker
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// RealClock 系统时钟
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (RealClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) Chan() <-chan time.Time {
+	return t.C
+}
+
+// FakeClock 手动推进的时钟，用于测试。
+// Sleep 阻塞到时间被推进到唤醒点，Ticker 在推进跨过触发点时发送（与 time.Ticker 一样丢弃来不及接收的触发）
+type FakeClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers []*fakeSleeper
+	tickers  []*fakeTicker
+}
+
+type fakeSleeper struct {
+	until time.Time
+	done  chan struct{}
+}
+
+// NewFakeClock 创建从 now 开始的时钟
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	sleeper := &fakeSleeper{until: c.now.Add(d), done: make(chan struct{})}
+	c.sleepers = append(c.sleepers, sleeper)
+	c.mu.Unlock()
+
+	<-sleeper.done
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ticker := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advance 推进时间，触发到期的 Ticker 并唤醒到期的 Sleep
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.set(c.now.Add(d))
+	c.mu.Unlock()
+}
+
+// Set 设置当前时间，可以向前回拨以模拟时钟跳变，回拨时不会触发 Ticker 和 Sleep
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	c.set(now)
+	c.mu.Unlock()
+}
+
+// Sleepers 返回正在 Sleep 的 goroutine 数
+func (c *FakeClock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sleepers)
+}
+
+func (c *FakeClock) set(now time.Time) {
+	c.now = now
+
+	for _, ticker := range c.tickers {
+		for !ticker.next.After(now) {
+			select {
+			case ticker.c <- ticker.next:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+
+	sleepers := c.sleepers[:0]
+	for _, sleeper := range c.sleepers {
+		if sleeper.until.After(now) {
+			sleepers = append(sleepers, sleeper)
+		} else {
+			close(sleeper.done)
+		}
+	}
+	c.sleepers = sleepers
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock_Sleep(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	done := make(chan struct{})
+	go func() {
+		clock.Sleep(5 * time.Second)
+		close(done)
+	}()
+	for clock.Sleepers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(4 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned before the clock reached its deadline")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep did not return after the clock reached its deadline")
+	}
+	if got := clock.Now(); !got.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("Now() = %v; want %v", got, start.Add(5*time.Second))
+	}
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case tick := <-ticker.Chan():
+		t.Fatalf("unexpected tick %v", tick)
+	default:
+	}
+
+	clock.Advance(3 * time.Second) // 跨过 3 个触发点，只保留第一个未接收的触发
+	if tick := <-ticker.Chan(); !tick.Equal(start.Add(time.Second)) {
+		t.Errorf("tick = %v; want %v", tick, start.Add(time.Second))
+	}
+	select {
+	case tick := <-ticker.Chan():
+		t.Fatalf("expected dropped ticks, got %v", tick)
+	default:
+	}
+
+	clock.Set(start) // 回拨不触发
+	clock.Advance(time.Second)
+	select {
+	case tick := <-ticker.Chan():
+		t.Fatalf("unexpected tick after set backwards %v", tick)
+	default:
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	select {
+	case tick := <-ticker.Chan():
+		t.Fatalf("unexpected tick after Stop %v", tick)
+	default:
+	}
+}